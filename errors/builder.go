@@ -17,6 +17,30 @@ var (
 // ErrorBuilder is a callback that transform the given error to a gapi Error.
 type ErrorBuilder func(err error) Error
 
+// Builders is a list of ErrorBuilder callbacks that can be used to wrap errors
+// independently of the builders registered with AddErrorBuilders.
+type Builders []ErrorBuilder
+
+// Wrap will wrap the given error using the Builders callbacks and return a new Error.
+// If no builder handles the error, it is wrapped as an internal error. (see Wrap)
+func (b Builders) Wrap(err error) Error {
+	if err == nil {
+		return nil
+	}
+
+	if castedErr, ok := err.(Error); ok {
+		return castedErr
+	}
+
+	for _, builder := range b {
+		if gErr := builder(err); gErr != nil {
+			return gErr
+		}
+	}
+
+	return newInternalError(err)
+}
+
 // AddErrorBuilders appends custom errors.ErrorBuilder.
 // These callbacks are executed when wrapping an error with errors.Wrap().
 func AddErrorBuilders(builders ...ErrorBuilder) {
@@ -43,24 +43,16 @@ type FullError struct {
 }
 
 // Wrap will wrap the given error and return a new Error.
+// The custom ErrorBuilder callbacks registered with AddErrorBuilders are executed first.
 func Wrap(err error) Error {
-	if err == nil {
-		return nil
-	}
-
-	if castedErr, ok := err.(Error); ok {
-		return castedErr
-	}
-
-	for _, builder := range errorBuilders {
-		if gErr := builder(err); gErr != nil {
-			return gErr
-		}
-	}
+	return Builders(errorBuilders).Wrap(err)
+}
 
+// newInternalError wraps the given error as an internal error.
+func newInternalError(err error) Error {
 	callerName, caller, callstack := GetCallers()
 
-	newErr := &FullError{
+	return &FullError{
 		userMessage:  err.Error(),
 		kind:         "internal_error",
 		errorMessage: err.Error(),
@@ -71,8 +63,6 @@ func Wrap(err error) Error {
 		caller:       caller,
 		callstack:    callstack,
 	}
-
-	return newErr
 }
 
 // Err creates a new Error.
@@ -0,0 +1,25 @@
+/*
+Package gapitest provides an in-memory test harness for gapi handlers.
+
+A Harness registers a handler.Handler or a handler.Factory on a route template, with the same middleware chain
+as server.AddHandler and server.AddHandlerFactory, and serves requests built with a RequestBuilder without any network.
+
+Each Response holds the raw value and the errors.Error returned by the handler chain,
+along with the status code, headers and body written by the response writer.
+
+	func TestGetUser(t *testing.T) {
+		h := gapitest.New(t, http.MethodGet, "/users/{id}", getUserHandler)
+
+		resp := h.Request().
+			WithPathParam("id", "42").
+			WithHeader("Authorization", "Bearer token").
+			Do()
+
+		resp.AssertStatus(http.StatusOK)
+		user := gapitest.BodyAs[User](resp)
+
+		resp = h.Request().WithPathParam("id", "unknown").Do()
+		resp.AssertErrorKind("user_not_found")
+	}
+*/
+package gapitest
@@ -0,0 +1,117 @@
+package gapitest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/server"
+)
+
+// Option is an option to modify the default configuration of a Harness.
+type Option func(*harnessOptions)
+
+// WithApp registers the handler on the App router, with the App middlewares and error builders.
+// By default, the handler is registered on a new router with the package-level server functions,
+// using middleware.Defaults and the errors.AddErrorBuilders builders.
+func WithApp(app *server.App) Option {
+	return func(o *harnessOptions) {
+		o.app = app
+	}
+}
+
+type harnessOptions struct {
+	app *server.App
+}
+
+// Harness serves in-memory requests to a handler registered on a route template.
+type Harness struct {
+	t      testing.TB
+	router *mux.Router
+	route  *mux.Route
+	method string
+}
+
+// New returns a new Harness serving the given handler, as registered by server.AddHandler.
+func New(t testing.TB, method, route string, h handler.Handler, opts ...Option) *Harness {
+	return newHarness(t, method, route, func(o harnessOptions, r *mux.Router) {
+		if o.app != nil {
+			o.app.Group("", resultRecorder{}).AddHandler(method, route, h)
+			return
+		}
+
+		server.Group(r, "", resultRecorder{}).AddHandler(method, route, h)
+	}, opts...)
+}
+
+// NewFactory returns a new Harness serving the handlers built by the given factory, as registered by server.AddHandlerFactory.
+func NewFactory(t testing.TB, method, route string, f handler.Factory, opts ...Option) *Harness {
+	return newHarness(t, method, route, func(o harnessOptions, r *mux.Router) {
+		if o.app != nil {
+			o.app.Group("", resultRecorder{}).AddHandlerFactory(method, route, f)
+			return
+		}
+
+		server.Group(r, "", resultRecorder{}).AddHandlerFactory(method, route, f)
+	}, opts...)
+}
+
+func newHarness(t testing.TB, method, route string, register func(o harnessOptions, r *mux.Router), opts ...Option) *Harness {
+	t.Helper()
+
+	var o harnessOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	router := server.NewMux()
+	if o.app != nil {
+		router = o.app.Router()
+	}
+
+	register(o, router)
+
+	h := &Harness{
+		t:      t,
+		router: router,
+		method: method,
+	}
+
+	_ = router.Walk(func(r *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := r.GetPathTemplate()
+		if err != nil || template != route {
+			return nil
+		}
+
+		methods, _ := r.GetMethods()
+		for _, m := range methods {
+			if m == method {
+				h.route = r
+			}
+		}
+
+		return nil
+	})
+
+	if h.route == nil {
+		t.Fatalf("gapitest: route %s %s not found", method, route)
+	}
+
+	return h
+}
+
+// Request returns a new RequestBuilder for the Harness route.
+func (h *Harness) Request() *RequestBuilder {
+	return &RequestBuilder{
+		harness: h,
+		method:  h.method,
+		header:  make(http.Header),
+	}
+}
+
+// Router returns the router the handler is registered on.
+func (h *Harness) Router() *mux.Router {
+	return h.router
+}
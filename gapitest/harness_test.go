@@ -0,0 +1,105 @@
+package gapitest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/server"
+	"github.com/mwm-io/gapi/typed"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type updateUserRequest struct {
+	ID     int    `path:"id"`
+	Tenant string `header:"X-Tenant" required:"true"`
+	Name   string `json:"name"`
+}
+
+type getUserHandler struct {
+	handler.WithMiddlewares
+
+	params struct {
+		ID int `path:"id"`
+	}
+}
+
+func newGetUserHandler() handler.Handler {
+	h := &getUserHandler{}
+	h.MiddlewareList = []handler.Middleware{
+		middleware.PathParameters{Parameters: &h.params},
+	}
+
+	return h
+}
+
+func (h *getUserHandler) Serve(http.ResponseWriter, *http.Request) (interface{}, error) {
+	if h.params.ID != 42 {
+		return nil, errors.NotFound("user_not_found", "user %d not found", h.params.ID)
+	}
+
+	return user{ID: h.params.ID, Name: "gopher"}, nil
+}
+
+func TestHarness(t *testing.T) {
+	h := NewFactory(t, http.MethodGet, "/users/{id}", newGetUserHandler)
+
+	resp := h.Request().WithPathParam("id", "42").Do()
+	resp.AssertStatus(http.StatusOK)
+	resp.AssertNoError()
+	resp.AssertJSON(`{"id":42,"name":"gopher"}`)
+
+	assert.Equal(t, user{ID: 42, Name: "gopher"}, RawAs[user](resp))
+
+	resp = h.Request().WithPathParam("id", "1").Do()
+	resp.AssertError(http.StatusNotFound, "user_not_found")
+	resp.AssertJSON(`{"kind":"user_not_found","message":"user 1 not found"}`)
+}
+
+func TestHarness_Typed(t *testing.T) {
+	update := typed.New(func(ctx context.Context, req updateUserRequest) (user, error) {
+		return user{ID: req.ID, Name: req.Tenant + "/" + req.Name}, nil
+	})
+
+	h := New(t, http.MethodPut, "/users/{id}", update)
+
+	resp := h.Request().
+		WithPathParam("id", "7").
+		WithHeader("X-Tenant", "mwm").
+		WithJSONBody(map[string]string{"name": "gopher"}).
+		Do()
+
+	resp.AssertStatus(http.StatusOK)
+	assert.Equal(t, user{ID: 7, Name: "mwm/gopher"}, BodyAs[user](resp))
+
+	resp = h.Request().WithPathParam("id", "7").Do()
+	resp.AssertError(http.StatusBadRequest, "missing_param")
+}
+
+type unauthorizedMiddleware struct{}
+
+func (m unauthorizedMiddleware) Wrap(handler.Handler) handler.Handler {
+	return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+		return nil, errors.Unauthorized("missing_token", "missing token")
+	})
+}
+
+func TestHarness_AppMiddlewareError(t *testing.T) {
+	app := server.NewApp()
+	app.UseMiddlewares(unauthorizedMiddleware{})
+
+	h := NewFactory(t, http.MethodGet, "/users/{id}", newGetUserHandler, WithApp(app))
+
+	resp := h.Request().WithPathParam("id", "42").Do()
+	resp.AssertError(http.StatusUnauthorized, "missing_token")
+	assert.Nil(t, resp.Raw)
+}
@@ -0,0 +1,185 @@
+package gapitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+// RequestBuilder builds a request to the Harness route.
+type RequestBuilder struct {
+	harness     *Harness
+	method      string
+	pathParams  []string
+	query       url.Values
+	header      http.Header
+	cookies     []*http.Cookie
+	body        io.Reader
+	contentType string
+	ctx         context.Context
+	err         error
+}
+
+// WithPathParam sets the value of a path parameter of the route template.
+func (b *RequestBuilder) WithPathParam(name, value string) *RequestBuilder {
+	b.pathParams = append(b.pathParams, name, value)
+	return b
+}
+
+// WithQuery adds a query parameter.
+func (b *RequestBuilder) WithQuery(name string, values ...string) *RequestBuilder {
+	if b.query == nil {
+		b.query = make(url.Values)
+	}
+
+	b.query[name] = append(b.query[name], values...)
+	return b
+}
+
+// WithHeader adds a header.
+func (b *RequestBuilder) WithHeader(name, value string) *RequestBuilder {
+	b.header.Add(name, value)
+	return b
+}
+
+// WithCookie adds a cookie.
+func (b *RequestBuilder) WithCookie(name, value string) *RequestBuilder {
+	b.cookies = append(b.cookies, &http.Cookie{Name: name, Value: value})
+	return b
+}
+
+// WithBody sets the raw body and its content type.
+func (b *RequestBuilder) WithBody(contentType string, body []byte) *RequestBuilder {
+	b.body = bytes.NewReader(body)
+	b.contentType = contentType
+	return b
+}
+
+// WithJSONBody sets the body to the JSON encoding of v.
+func (b *RequestBuilder) WithJSONBody(v interface{}) *RequestBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	return b.WithBody("application/json", body)
+}
+
+// WithFormBody sets the body to the given application/x-www-form-urlencoded values.
+func (b *RequestBuilder) WithFormBody(values url.Values) *RequestBuilder {
+	return b.WithBody("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// WithMultipartBody sets the body to a multipart/form-data body with the given values and files content, by field name.
+func (b *RequestBuilder) WithMultipartBody(values url.Values, files map[string][]byte) *RequestBuilder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, fieldValues := range values {
+		for _, value := range fieldValues {
+			if err := writer.WriteField(name, value); err != nil {
+				b.err = err
+				return b
+			}
+		}
+	}
+
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name)
+		if err != nil {
+			b.err = err
+			return b
+		}
+
+		if _, err := part.Write(content); err != nil {
+			b.err = err
+			return b
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		b.err = err
+		return b
+	}
+
+	return b.WithBody(writer.FormDataContentType(), body.Bytes())
+}
+
+// WithContext sets the context of the request.
+func (b *RequestBuilder) WithContext(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Build returns the built *http.Request. It fails the test if the request can't be built.
+func (b *RequestBuilder) Build() *http.Request {
+	t := b.harness.t
+	t.Helper()
+
+	if b.err != nil {
+		t.Fatalf("gapitest: failed to build request: %s", b.err.Error())
+	}
+
+	u, err := b.harness.route.URLPath(b.pathParams...)
+	if err != nil {
+		t.Fatalf("gapitest: failed to build request path: %s", err.Error())
+	}
+
+	u.RawQuery = b.query.Encode()
+
+	r := httptest.NewRequest(b.method, u.String(), b.body)
+	for name, values := range b.header {
+		r.Header[name] = values
+	}
+
+	if b.contentType != "" && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", b.contentType)
+	}
+
+	for _, cookie := range b.cookies {
+		r.AddCookie(cookie)
+	}
+
+	if b.ctx != nil {
+		r = r.WithContext(b.ctx)
+	}
+
+	return r
+}
+
+// Do serves the request and returns its Response.
+func (b *RequestBuilder) Do() *Response {
+	b.harness.t.Helper()
+
+	r := b.Build()
+
+	resp := &Response{
+		t: b.harness.t,
+	}
+
+	res := &result{}
+	r = r.WithContext(context.WithValue(r.Context(), resultKey{}, res))
+
+	recorder := httptest.NewRecorder()
+	b.harness.router.ServeHTTP(recorder, r)
+
+	resp.Recorder = recorder
+	resp.StatusCode = recorder.Code
+	resp.Header = recorder.Header()
+	resp.Body = recorder.Body.Bytes()
+
+	if res.recorded {
+		resp.Raw = res.raw
+		resp.Err = toError(res.err)
+	} else {
+		resp.Err = errorFromBody(resp.StatusCode, resp.Body)
+	}
+
+	return resp
+}
@@ -0,0 +1,207 @@
+package gapitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+)
+
+// Response is the result of a request served by a Harness.
+type Response struct {
+	t testing.TB
+
+	// Raw is the response returned by the handler chain, before being encoded.
+	Raw interface{}
+	// Err is the error returned by the handler chain, nil if the handler succeeded.
+	// If the request failed before the handler middlewares (ie: in an App middleware), it is decoded from the body.
+	Err errors.Error
+	// StatusCode is the written status code.
+	StatusCode int
+	// Header is the written header.
+	Header http.Header
+	// Body is the written body.
+	Body []byte
+	// Recorder is the recorder the response has been written into.
+	Recorder *httptest.ResponseRecorder
+}
+
+// AssertStatus asserts that the written status code is the expected one.
+func (r *Response) AssertStatus(expected int) bool {
+	r.t.Helper()
+
+	if r.StatusCode != expected {
+		r.t.Errorf("gapitest: unexpected status code %d, expected %d, body: %s", r.StatusCode, expected, r.Body)
+		return false
+	}
+
+	return true
+}
+
+// AssertNoError asserts that the handler chain didn't return any error.
+func (r *Response) AssertNoError() bool {
+	r.t.Helper()
+
+	if r.Err != nil {
+		r.t.Errorf("gapitest: unexpected error: %s", r.Err.Error())
+		return false
+	}
+
+	return true
+}
+
+// AssertErrorKind asserts that the handler chain returned an error of the expected kind.
+func (r *Response) AssertErrorKind(kind string) bool {
+	r.t.Helper()
+
+	if r.Err == nil {
+		r.t.Errorf("gapitest: expected an error of kind %s", kind)
+		return false
+	}
+
+	if r.Err.Kind() != kind {
+		r.t.Errorf("gapitest: unexpected error kind %s, expected %s, error: %s", r.Err.Kind(), kind, r.Err.Error())
+		return false
+	}
+
+	return true
+}
+
+// AssertError asserts that the handler chain returned an error of the expected kind and status code.
+func (r *Response) AssertError(statusCode int, kind string) bool {
+	r.t.Helper()
+
+	if !r.AssertErrorKind(kind) {
+		return false
+	}
+
+	if r.Err.StatusCode() != statusCode {
+		r.t.Errorf("gapitest: unexpected error status code %d, expected %d", r.Err.StatusCode(), statusCode)
+		return false
+	}
+
+	return r.AssertStatus(statusCode)
+}
+
+// AssertJSON asserts that the written body is equivalent to the expected JSON.
+func (r *Response) AssertJSON(expected string) bool {
+	r.t.Helper()
+
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		r.t.Errorf("gapitest: expected value %s is not valid JSON: %s", expected, err.Error())
+		return false
+	}
+
+	if err := json.Unmarshal(r.Body, &actualValue); err != nil {
+		r.t.Errorf("gapitest: body %s is not valid JSON: %s", r.Body, err.Error())
+		return false
+	}
+
+	if !reflect.DeepEqual(expectedValue, actualValue) {
+		r.t.Errorf("gapitest: unexpected body %s, expected %s", r.Body, expected)
+		return false
+	}
+
+	return true
+}
+
+// AssertHeader asserts that the written header has the expected value.
+func (r *Response) AssertHeader(name, expected string) bool {
+	r.t.Helper()
+
+	if actual := r.Header.Get(name); actual != expected {
+		r.t.Errorf("gapitest: unexpected %s header %q, expected %q", name, actual, expected)
+		return false
+	}
+
+	return true
+}
+
+// BodyAs decodes the JSON body of the response into a new T. It fails the test if the body can't be decoded.
+func BodyAs[T any](r *Response) T {
+	r.t.Helper()
+
+	var v T
+	if err := json.Unmarshal(r.Body, &v); err != nil {
+		r.t.Fatalf("gapitest: failed to decode body %s: %s", r.Body, err.Error())
+	}
+
+	return v
+}
+
+// RawAs returns the raw response as a T. It fails the test if the raw response is not a T.
+func RawAs[T any](r *Response) T {
+	r.t.Helper()
+
+	v, ok := r.Raw.(T)
+	if !ok {
+		r.t.Fatalf("gapitest: raw response is a %T", r.Raw)
+	}
+
+	return v
+}
+
+// toError converts the error returned by the handler chain into an errors.Error.
+func toError(err error) errors.Error {
+	if err == nil {
+		return nil
+	}
+
+	if gErr, ok := err.(errors.Error); ok {
+		return gErr
+	}
+
+	return errors.Wrap(err)
+}
+
+// resultKey is the context key of the *result of a request served by a Harness.
+type resultKey struct{}
+
+// result is the response and the error returned by the handler chain.
+type result struct {
+	recorded bool
+	raw      interface{}
+	err      error
+}
+
+// resultRecorder is a middleware recording the result of the handler chain into the *result of the request context.
+// It is a route middleware: it is executed after the default middlewares (ResponseWriter, Log, Recover...)
+// and before the handler middlewares.
+type resultRecorder struct{}
+
+// Wrap implements the request.Middleware interface
+func (m resultRecorder) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		resp, err := h.Serve(w, r)
+
+		if res, ok := r.Context().Value(resultKey{}).(*result); ok {
+			res.recorded = true
+			res.raw = resp
+			res.err = err
+		}
+
+		return resp, err
+	})
+}
+
+// errorFromBody decodes the error written by the response writer, if any.
+func errorFromBody(statusCode int, body []byte) errors.Error {
+	if statusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	var written struct {
+		Kind    string `json:"kind"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &written); err != nil || written.Kind == "" {
+		return errors.Err("unknown", "%s", body).WithStatus(statusCode)
+	}
+
+	return errors.Err(written.Kind, "%s", written.Message).WithStatus(statusCode)
+}
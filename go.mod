@@ -10,6 +10,7 @@ require (
 	github.com/stretchr/testify v1.8.1
 	github.com/swaggest/openapi-go v0.2.24
 	go.uber.org/zap v1.24.0
+	golang.org/x/net v0.17.0
 )
 
 require (
@@ -20,6 +21,7 @@ require (
 	github.com/swaggest/refl v1.1.0 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
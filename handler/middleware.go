@@ -53,3 +53,16 @@ func (b ByWeight) Less(i, j int) bool {
 func (b ByWeight) Swap(i, j int) {
 	b[i], b[j] = b[j], b[i]
 }
+
+// BindingMiddleware is a Middleware writing request-scoped data into a target pointer.
+// (ie: middleware.PathParameters, middleware.BodyDecoder...)
+//
+// It is used to detect handlers storing request-scoped data that are shared between requests.
+type BindingMiddleware interface {
+	Middleware
+	// BindingTarget returns the pointer the middleware writes into.
+	BindingTarget() interface{}
+	// WithBindingTarget returns a copy of the middleware writing into the given pointer.
+	// The given pointer has the same type as the BindingTarget.
+	WithBindingTarget(target interface{}) Middleware
+}
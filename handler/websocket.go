@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mwm-io/gapi/response"
+	"github.com/mwm-io/gapi/websocket"
+)
+
+// WebSocket is a Handler upgrading the request to a websocket connection.
+//
+// It is registered like any other Handler (ie: server.AddHandler), so the middlewares are executed for the upgrade request:
+// the request is logged, authenticated... before being upgraded, and an error returned by a middleware is written
+// as a regular http response.
+// Once upgraded, the connection is served by Handle, and closed with the close code computed from the error it returns.
+// (see websocket.CloseCode)
+type WebSocket struct {
+	WithMiddlewares
+
+	// Options configures the upgraded connection. (ie: the Encoder and Decoder of the messages)
+	Options websocket.Options
+	// Handle serves the upgraded connection. Its context is cancelled when the connection is closed
+	// or when the server is shutting down.
+	Handle func(ctx context.Context, conn *websocket.Conn) error
+}
+
+// NewWebSocket returns a new WebSocket serving the upgraded connections with the given function.
+func NewWebSocket(handle func(ctx context.Context, conn *websocket.Conn) error, middlewares ...Middleware) *WebSocket {
+	return &WebSocket{
+		WithMiddlewares: WithMiddlewares{MiddlewareList: middlewares},
+		Handle:          handle,
+	}
+}
+
+// Serve implements the Handler interface.
+// It returns a response.Hijacked once the connection is upgraded, with the error returned by Handle
+// unless the connection has been closed normally.
+func (h WebSocket) Serve(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	conn, err := websocket.Upgrade(w, r, h.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Handle(conn.Context(), conn)
+	_ = conn.CloseWithError(err)
+
+	if websocket.IsNormalClosure(err) || err == context.Canceled {
+		return response.Hijacked{}, nil
+	}
+
+	return response.Hijacked{}, err
+}
@@ -0,0 +1,36 @@
+/*
+Package lifecycle runs several long-running processes in the same program (http servers, message consumers, workers...)
+and coordinates their shutdown.
+
+A Manager starts all its processes, and stops all of them as soon as:
+
+  - the program receives a stop signal
+  - one of the processes fails
+  - its context is done
+
+When stopping, the context given to the processes is cancelled, the shutdown functions of the processes are executed
+concurrently, then the shutdown hooks are executed in the reverse order of their registration,
+all of them within the StopTimeout.
+All the errors are aggregated in the error returned by Run.
+
+	m := lifecycle.NewManager(lifecycle.WithStopTimeout(10 * time.Second))
+
+	srv := server.NewServer(r)
+	m.Add("http", func(ctx context.Context) error {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+
+		return nil
+	}, srv.Shutdown)
+
+	m.Add("consumer", consumer.Run, nil)
+	m.AddShutdownHook("database", func(ctx context.Context) error {
+		return db.Close()
+	})
+
+	if err := m.Run(); err != nil {
+		log.Fatal(err)
+	}
+*/
+package lifecycle
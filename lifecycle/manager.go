@@ -0,0 +1,337 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	gLog "github.com/mwm-io/gapi/log"
+)
+
+// DefaultStopTimeout is the default time given to the shutdown hooks and the processes to stop.
+const DefaultStopTimeout = 3 * time.Second
+
+// ErrStopTimeout is the error of a process still running when the StopTimeout expires.
+var ErrStopTimeout = errors.New("process did not stop before the stop timeout")
+
+// Option is an option to modify the default configuration of a Manager.
+type Option func(*Manager)
+
+// WithStopTimeout sets the time given to the shutdown hooks and the processes to stop.
+// By default, DefaultStopTimeout.
+func WithStopTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.stopTimeout = d
+	}
+}
+
+// WithStopSignals specify on which os.Signal the processes must be stopped.
+// By default, os.Interrupt, syscall.SIGINT and syscall.SIGTERM
+func WithStopSignals(signals ...os.Signal) Option {
+	return func(m *Manager) {
+		m.stopSignals = signals
+	}
+}
+
+// WithGracePeriod sets the time to wait after the stop began (and the OnStop functions are called)
+// before cancelling the processes and executing the shutdown hooks.
+// It gives load balancers the time to stop routing traffic to the program.
+// By default, there is no grace period.
+func WithGracePeriod(d time.Duration) Option {
+	return func(m *Manager) {
+		m.gracePeriod = d
+	}
+}
+
+// WithContext specify the parent context of the processes. The processes are stopped when it is done.
+// By default, context.Background.
+func WithContext(ctx context.Context) Option {
+	return func(m *Manager) {
+		m.ctx = ctx
+	}
+}
+
+// Manager starts named processes and coordinates their shutdown. (see package documentation)
+type Manager struct {
+	stopTimeout time.Duration
+	gracePeriod time.Duration
+	stopSignals []os.Signal
+	ctx         context.Context
+
+	mu        sync.Mutex
+	processes []namedFunc
+	shutdowns []namedFunc
+	hooks     []namedFunc
+	onStop    []func()
+}
+
+type namedFunc struct {
+	name string
+	f    func(ctx context.Context) error
+}
+
+// NewManager returns a new Manager without any process.
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		stopTimeout: DefaultStopTimeout,
+		stopSignals: []os.Signal{os.Interrupt, syscall.SIGINT, syscall.SIGTERM},
+		ctx:         context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Add registers a process started by Run.
+// The process must return when its context is cancelled, or when its shutdown function is executed.
+// The shutdown functions of all the processes are executed concurrently, before the shutdown hooks.
+// (ie: all the servers are drained at the same time, within the StopTimeout)
+// shutdown can be nil if cancelling the context is enough to stop the process.
+func (m *Manager) Add(name string, process func(ctx context.Context) error, shutdown func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.processes = append(m.processes, namedFunc{name: name, f: process})
+	if shutdown != nil {
+		m.shutdowns = append(m.shutdowns, namedFunc{name: name, f: shutdown})
+	}
+}
+
+// AddShutdownHook registers a function executed when stopping, once the processes shutdown functions returned,
+// and after the shutdown hooks registered after it. (ie: closing a database connection used by the processes)
+func (m *Manager) AddShutdownHook(name string, hook func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, namedFunc{name: name, f: hook})
+}
+
+// OnStop registers a function called as soon as the Manager starts stopping, before the shutdown hooks.
+// (ie: to make the readiness probes fail)
+func (m *Manager) OnStop(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onStop = append(m.onStop, f)
+}
+
+// Run starts all the processes and locks until they are all stopped.
+//
+// The processes are stopped when a stop signal is received, when one of them fails or when the Manager context is done.
+// The OnStop functions are called, and after the grace period (see WithGracePeriod) the processes context is cancelled,
+// the processes shutdown functions are executed concurrently, then the shutdown hooks are executed in the reverse order
+// of their registration.
+// Run returns when all the processes returned, or when the StopTimeout expires.
+//
+// The returned error is nil or an Errors, containing a *ProcessError for each failed process or shutdown hook.
+func (m *Manager) Run() error {
+	m.mu.Lock()
+	processes := append([]namedFunc{}, m.processes...)
+	shutdowns := append([]namedFunc{}, m.shutdowns...)
+	hooks := append([]namedFunc{}, m.hooks...)
+	onStop := append([]func(){}, m.onStop...)
+	m.mu.Unlock()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, m.stopSignals...)
+	defer signal.Stop(done)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(processes))
+	for _, p := range processes {
+		go func(p namedFunc) {
+			results <- result{name: p.name, err: p.f(ctx)}
+		}(p)
+	}
+
+	var errs Errors
+	running := make(map[string]int, len(processes))
+	for _, p := range processes {
+		running[p.name]++
+	}
+
+	logger := gLog.Info(m.ctx)
+
+wait:
+	for len(running) != 0 {
+		select {
+		case sig := <-done:
+			logger.LogMsg("%s signal received: stopping", sig)
+			break wait
+
+		case <-m.ctx.Done():
+			logger.LogMsg("context done: stopping")
+			break wait
+
+		case res := <-results:
+			release(running, res.name)
+			if res.err != nil {
+				errs = append(errs, &ProcessError{Name: res.name, Err: res.err})
+				gLog.Error(m.ctx).With(zap.String("process", res.name)).LogMsg("process %s failed: stopping", res.name)
+				break wait
+			}
+		}
+	}
+
+	for _, f := range onStop {
+		f()
+	}
+
+	if m.gracePeriod > 0 {
+		logger.LogMsg("waiting %s grace period before shutting down", m.gracePeriod)
+		m.wait(m.gracePeriod, done)
+	}
+
+	logger.LogMsg("shutting down")
+	cancel()
+
+	stopCtx, stopCancel := context.WithTimeout(stopContext(m.ctx), m.stopTimeout)
+	defer stopCancel()
+
+	shutdownErrs := make([]error, len(shutdowns))
+
+	var wg sync.WaitGroup
+	for i, shutdown := range shutdowns {
+		wg.Add(1)
+		go func(i int, shutdown namedFunc) {
+			defer wg.Done()
+			shutdownErrs[i] = shutdown.f(stopCtx)
+		}(i, shutdown)
+	}
+	wg.Wait()
+
+	for i, err := range shutdownErrs {
+		if err != nil {
+			errs = append(errs, &ProcessError{Name: shutdowns[i].name, Err: err})
+		}
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].f(stopCtx); err != nil {
+			errs = append(errs, &ProcessError{Name: hooks[i].name, Err: err})
+		}
+	}
+
+	for len(running) != 0 {
+		select {
+		case res := <-results:
+			release(running, res.name)
+			if res.err != nil && !errors.Is(res.err, context.Canceled) {
+				errs = append(errs, &ProcessError{Name: res.name, Err: res.err})
+			}
+
+		case <-stopCtx.Done():
+			for name := range running {
+				errs = append(errs, &ProcessError{Name: name, Err: ErrStopTimeout})
+			}
+
+			return errs
+		}
+	}
+
+	logger.LogMsg("stopped")
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// wait waits for the given duration. It returns early if a second stop signal is received or the context is done.
+func (m *Manager) wait(d time.Duration, signals <-chan os.Signal) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-signals:
+	case <-stopContext(m.ctx).Done():
+	}
+}
+
+// release removes a process from the running processes.
+func release(running map[string]int, name string) {
+	running[name]--
+	if running[name] <= 0 {
+		delete(running, name)
+	}
+}
+
+// stopContext returns the parent context of the stop timeout: ctx, or a new context if ctx is already done.
+func stopContext(ctx context.Context) context.Context {
+	if ctx.Err() != nil {
+		return context.Background()
+	}
+
+	return ctx
+}
+
+// ProcessError is the error of a failed process or shutdown hook.
+type ProcessError struct {
+	Name string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Err.Error())
+}
+
+// Unwrap implements the errors.Unwrap interface
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is the aggregation of the errors of a Manager.
+type Errors []error
+
+// Error implements the error interface
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is returns true if one of the aggregated errors matches target. (see errors.Is)
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first aggregated error matching target, and if so, sets target to that error. (see errors.As)
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
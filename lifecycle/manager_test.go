@@ -0,0 +1,120 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	errConsumer := errors.New("connection lost")
+	errCache := errors.New("cache close failed")
+
+	var stopOrder []string
+
+	m := NewManager(WithStopSignals(syscall.SIGUSR1), WithStopTimeout(100*time.Millisecond))
+	m.OnStop(func() {
+		stopOrder = append(stopOrder, "on-stop")
+	})
+	m.AddShutdownHook("cache", func(context.Context) error {
+		stopOrder = append(stopOrder, "cache")
+		return errCache
+	})
+
+	stopped := make(chan struct{})
+	m.Add("http", func(context.Context) error {
+		<-stopped
+		return nil
+	}, func(context.Context) error {
+		stopOrder = append(stopOrder, "http")
+		close(stopped)
+		return nil
+	})
+	m.Add("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	m.Add("consumer", func(context.Context) error {
+		return errConsumer
+	}, nil)
+	m.Add("stuck", func(context.Context) error {
+		select {}
+	}, nil)
+
+	err := m.Run()
+
+	assert.Equal(t, []string{"on-stop", "http", "cache"}, stopOrder)
+	assert.ErrorIs(t, err, errConsumer)
+	assert.ErrorIs(t, err, errCache)
+	assert.ErrorIs(t, err, ErrStopTimeout)
+	var processErr *ProcessError
+	assert.True(t, errors.As(err, &processErr))
+	assert.Equal(t, "consumer", processErr.Name)
+
+	assert.EqualError(t, err, "consumer: connection lost; cache: cache close failed; stuck: process did not stop before the stop timeout")
+}
+
+func TestManagerStopSignal(t *testing.T) {
+	m := NewManager(WithStopSignals(syscall.SIGUSR1))
+	m.Add("signal", func(ctx context.Context) error {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+			return err
+		}
+
+		<-ctx.Done()
+		return nil
+	}, nil)
+
+	assert.NoError(t, m.Run())
+}
+
+func TestManagerConcurrentShutdown(t *testing.T) {
+	m := NewManager(WithStopSignals(syscall.SIGUSR1), WithStopTimeout(time.Second))
+
+	// Each shutdown function waits for the other one to start: they must run concurrently.
+	var started sync.WaitGroup
+	started.Add(2)
+
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	for _, name := range []string{"public", "admin"} {
+		stopped := make(chan struct{})
+		m.Add(name, func(context.Context) error {
+			<-stopped
+			return nil
+		}, func(ctx context.Context) error {
+			defer close(stopped)
+
+			started.Done()
+
+			select {
+			case <-allStarted:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	m.Add("signal", func(ctx context.Context) error {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+			return err
+		}
+
+		<-ctx.Done()
+		return nil
+	}, nil)
+
+	start := time.Now()
+	assert.NoError(t, m.Run())
+	assert.Less(t, time.Since(start), time.Second)
+}
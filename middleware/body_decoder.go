@@ -17,28 +17,39 @@ import (
 
 // Body return a preconfigured BodyDecoder with :
 //   - BodyDecoder.DefaultContentType = `application/json`
-//   - BodyDecoder.Decoders with all referenced Decoder
+//   - BodyDecoder.Decoders with all referenced Decoder, and the form decoders if the body has `formData` fields
+//   - BodyDecoder.MaxBodySize = DefaultMaxBodySize
+//   - BodyDecoder.MaxDecompressionRatio = DefaultMaxDecompressionRatio
 func Body(bodyPtr interface{}) BodyDecoder {
 	return BodyDecoder{
-		BodyPtr:            bodyPtr,
-		Decoders:           DecoderByContentType,
-		DefaultContentType: "application/json",
-		SkipValidation:     false,
+		BodyPtr:               bodyPtr,
+		Decoders:              decodersFor(bodyPtr),
+		DefaultContentType:    "application/json",
+		SkipValidation:        false,
+		MaxBodySize:           DefaultMaxBodySize,
+		MaxDecompressionRatio: DefaultMaxDecompressionRatio,
 	}
 }
 
 // JsonBody return a preconfigured BodyDecoder with :
 //   - BodyDecoder.ForcedContentType = `application/json`
+//   - BodyDecoder.MaxBodySize = DefaultMaxBodySize
+//   - BodyDecoder.MaxDecompressionRatio = DefaultMaxDecompressionRatio
 func JsonBody(bodyPtr interface{}) BodyDecoder {
 	return BodyDecoder{
-		BodyPtr:           bodyPtr,
-		Decoders:          DecoderByContentType,
-		ForcedContentType: "application/json",
-		SkipValidation:    false,
+		BodyPtr:               bodyPtr,
+		Decoders:              DecoderByContentType,
+		ForcedContentType:     "application/json",
+		SkipValidation:        false,
+		MaxBodySize:           DefaultMaxBodySize,
+		MaxDecompressionRatio: DefaultMaxDecompressionRatio,
 	}
 }
 
 // BodyDecoder is a middleware that will Unmarshal the incoming request body into the Body field.
+//
+// Bodies with a gzip or deflate Content-Encoding are decompressed before being decoded.
+// Bodies larger than MaxBodySize, once decompressed, are rejected with errors.RequestEntityTooLarge (413).
 type BodyDecoder struct {
 	// BodyPtr is a pointer to the variable you want to unmarshal your request body into.
 	BodyPtr interface{}
@@ -50,6 +61,13 @@ type BodyDecoder struct {
 	SkipValidation bool
 	// ForcedContentType will always decode a body with this content-type.
 	ForcedContentType string
+	// MaxBodySize is the maximum size of the request body, once decompressed. If 0, there is no limit.
+	MaxBodySize int64
+	// MaxDecompressionRatio is the maximum ratio between the decompressed and the compressed body sizes,
+	// protecting against decompression bombs. If 0, there is no limit.
+	MaxDecompressionRatio int64
+	// RequireContentLength rejects the requests without Content-Length header with errors.LengthRequired (411).
+	RequireContentLength bool
 }
 
 // SetDefaultContentType set DefaultContentType and return current instance
@@ -70,6 +88,24 @@ func (m BodyDecoder) SetDecoders(decoders map[string]Decoder) BodyDecoder {
 	return m
 }
 
+// SetMaxBodySize set MaxBodySize and return current instance
+func (m BodyDecoder) SetMaxBodySize(maxBodySize int64) BodyDecoder {
+	m.MaxBodySize = maxBodySize
+	return m
+}
+
+// SetMaxDecompressionRatio set MaxDecompressionRatio and return current instance
+func (m BodyDecoder) SetMaxDecompressionRatio(ratio int64) BodyDecoder {
+	m.MaxDecompressionRatio = ratio
+	return m
+}
+
+// SetRequireContentLength set RequireContentLength and return current instance
+func (m BodyDecoder) SetRequireContentLength(required bool) BodyDecoder {
+	m.RequireContentLength = required
+	return m
+}
+
 // AddDecoder add a Decoder in field Decoders and return current instance
 func (m BodyDecoder) AddDecoder(contentType string, decoder Decoder) BodyDecoder {
 	m.Decoders[contentType] = decoder
@@ -92,34 +128,13 @@ func (m BodyDecoder) Wrap(h handler.Handler) handler.Handler {
 			return nil, errors.Err("invalid_body_receiver", "BodyPtr must be a pointer")
 		}
 
-		unmarshaler, err := m.resolveContentType(r)
-		if err != nil {
-			return nil, errors.BadRequest("invalid_content_type", "unable to resolve content type").
-				WithError(err)
-		}
-
-		var buffer bytes.Buffer
-		reader := io.TeeReader(r.Body, &buffer)
-
-		defer func() {
-			_ = r.Body.Close
-		}()
-
-		body, err := io.ReadAll(reader)
-		if err != nil {
-			return nil, errors.BadRequest("body_error", "failed to read body").
-				WithError(err)
-		}
+		defer RemoveMultipartForm(r)
 
-		if errUnmarshal := unmarshaler.Unmarshal(body, m.BodyPtr); errUnmarshal != nil {
-			return nil, errors.BadRequest("invalid_body_format", "failed to decode body").
-				WithError(errUnmarshal)
+		if err := m.decode(r); err != nil {
+			return nil, err
 		}
 
-		r.Body = io.NopCloser(bytes.NewReader(buffer.Bytes()))
-
-		err = validateRecursive(m.BodyPtr)
-		if err != nil {
+		if err := validateRecursive(m.BodyPtr); err != nil {
 			return nil, err
 		}
 
@@ -137,6 +152,64 @@ func (m BodyDecoder) Wrap(h handler.Handler) handler.Handler {
 	})
 }
 
+// decode reads the request body and unmarshal it into BodyPtr.
+// The request body is replaced by a new reader so it can be read again by the next handlers.
+func (m BodyDecoder) decode(r *http.Request) errors.Error {
+	unmarshaler, err := m.resolveContentType(r)
+	if err != nil {
+		return errors.BadRequest("invalid_content_type", "unable to resolve content type").
+			WithError(err)
+	}
+
+	if errLimit := m.limitBody(r); errLimit != nil {
+		return errLimit
+	}
+
+	if requestDecoder, ok := unmarshaler.(RequestDecoder); ok {
+		if errDecode := requestDecoder.DecodeRequest(r, m.BodyPtr); errDecode != nil {
+			if castedErr, casted := errDecode.(errors.Error); casted {
+				return castedErr
+			}
+
+			return errors.BadRequest("invalid_body_format", "failed to decode body").
+				WithError(errDecode)
+		}
+
+		return nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	if err != nil {
+		return bodyReadError(err)
+	}
+
+	if errUnmarshal := unmarshaler.Unmarshal(body, m.BodyPtr); errUnmarshal != nil {
+		if castedErr, casted := errUnmarshal.(errors.Error); casted {
+			return castedErr
+		}
+
+		return errors.BadRequest("invalid_body_format", "failed to decode body").
+			WithError(errUnmarshal)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return nil
+}
+
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m BodyDecoder) BindingTarget() interface{} {
+	return m.BodyPtr
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m BodyDecoder) WithBindingTarget(target interface{}) handler.Middleware {
+	m.BodyPtr = target
+	return m
+}
+
 // Doc implements the openapi.Documented interface
 func (m BodyDecoder) Doc(builder *openapi.DocBuilder) error {
 	if m.BodyPtr == nil {
@@ -147,6 +220,17 @@ func (m BodyDecoder) Doc(builder *openapi.DocBuilder) error {
 		builder.WithBody(m.BodyPtr, openapi.WithMimeType(contentType))
 	}
 
+	// Form decoders are only documented when the body can be decoded as a form.
+	if hasTaggedFields(m.BodyPtr, "formData") {
+		for _, decoder := range m.Decoders {
+			if documentedDecoder, ok := decoder.(openapi.Documented); ok {
+				if err := documentedDecoder.Doc(builder); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	builder.
 		WithError(400, "invalid_content_type", "Unable to resolve content type").
 		WithError(400, "body_error", "Failed to read request body").
@@ -156,6 +240,8 @@ func (m BodyDecoder) Doc(builder *openapi.DocBuilder) error {
 		WithError(400, "enum_validation_failed", "A field value is not in the allowed enum values").
 		WithError(400, "invalid_body", "Body validation failed")
 
+	m.docLimits(builder)
+
 	return builder.Error()
 }
 
@@ -192,6 +278,14 @@ func (m BodyDecoder) resolveContentType(r *http.Request) (Decoder, error) {
 	return result, nil
 }
 
+// RemoveMultipartForm removes the temporary files created when parsing a multipart/form-data request.
+// It is called by the middlewares decoding the request body once the request has been served.
+func RemoveMultipartForm(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
 func validateStruct(val reflect.Value) errors.Error {
 	for i := 0; i < val.NumField(); i++ {
 		typeOfParameters := val.Type()
@@ -240,6 +334,11 @@ func validateStruct(val reflect.Value) errors.Error {
 			continue
 		}
 
+		// The parameter has been sent with an empty value.
+		if tag, name := parameterTag(typeOfFieldI); name != "" {
+			return errors.BadRequest("missing_param", "%s %s is required", tag, name)
+		}
+
 		fieldName := typeOfFieldI.Name
 		switch jsonTag := typeOfFieldI.Tag.Get("json"); jsonTag {
 		case "-":
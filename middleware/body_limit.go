@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// Default limits of the BodyDecoder returned by Body and JsonBody.
+const (
+	// DefaultMaxBodySize is the default maximum size of a request body, once decompressed.
+	DefaultMaxBodySize = 10 << 20
+	// DefaultMaxDecompressionRatio is the default maximum ratio between the decompressed and the compressed body sizes.
+	DefaultMaxDecompressionRatio = 100
+)
+
+// minDecompressionRatioCheck is the decompressed size from which the decompression ratio is checked,
+// so that small and very repetitive bodies are accepted.
+const minDecompressionRatioCheck = 64 << 10
+
+// errDecompressionRatio is returned when reading a compressed body exceeding the maximum decompression ratio.
+type errDecompressionRatio struct {
+	ratio int64
+}
+
+func (e errDecompressionRatio) Error() string {
+	return "http: request body exceeds the maximum decompression ratio"
+}
+
+// limitBody checks the request body against the limits of the decoder, and replaces it with a reader
+// decompressing it according to its Content-Encoding header and failing once a limit is exceeded.
+// Errors returned by the new reader can be converted with bodyReadError.
+func (m BodyDecoder) limitBody(r *http.Request) errors.Error {
+	if m.RequireContentLength && r.ContentLength < 0 {
+		return errors.LengthRequired("length_required", "the Content-Length header is required")
+	}
+
+	if m.MaxBodySize > 0 && r.ContentLength > m.MaxBodySize {
+		return errors.RequestEntityTooLarge("body_too_large", "request body must not exceed %d bytes", m.MaxBodySize)
+	}
+
+	body := r.Body
+	if m.MaxBodySize > 0 {
+		body = http.MaxBytesReader(nil, body, m.MaxBodySize)
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		r.Body = body
+		return nil
+	}
+
+	compressed := &countingReader{reader: body}
+
+	var (
+		decompressor io.ReadCloser
+		err          error
+	)
+
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		decompressor, err = gzip.NewReader(compressed)
+	case EncodingDeflate:
+		decompressor, err = zlib.NewReader(compressed)
+	default:
+		return errors.UnsupportedMediaType("unsupported_content_encoding", "unsupported content-encoding %s", encoding)
+	}
+
+	if err != nil {
+		if castedErr := bodyReadError(err); castedErr.StatusCode() == http.StatusRequestEntityTooLarge {
+			return castedErr
+		}
+
+		return errors.BadRequest("invalid_content_encoding", "failed to decode %s body", encoding).
+			WithError(err)
+	}
+
+	var decompressed io.ReadCloser = &decompressedBody{
+		decompressor: decompressor,
+		body:         body,
+		compressed:   compressed,
+		maxRatio:     m.MaxDecompressionRatio,
+	}
+
+	if m.MaxBodySize > 0 {
+		decompressed = http.MaxBytesReader(nil, decompressed, m.MaxBodySize)
+	}
+
+	// The body is no longer encoded: the next handlers read it as it was sent without Content-Encoding.
+	r.Body = decompressed
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+
+	return nil
+}
+
+// bodyReadError converts an error returned while reading a body limited by limitBody.
+func bodyReadError(err error) errors.Error {
+	var maxBytesErr *http.MaxBytesError
+	if goErrors.As(err, &maxBytesErr) {
+		return errors.RequestEntityTooLarge("body_too_large", "request body must not exceed %d bytes", maxBytesErr.Limit).
+			WithError(err)
+	}
+
+	var ratioErr errDecompressionRatio
+	if goErrors.As(err, &ratioErr) {
+		return errors.RequestEntityTooLarge("decompression_ratio_exceeded", "decompressed request body must not exceed %d times its compressed size", ratioErr.ratio).
+			WithError(err)
+	}
+
+	return errors.BadRequest("body_error", "failed to read body").
+		WithError(err)
+}
+
+// docLimits documents the errors returned by limitBody.
+func (m BodyDecoder) docLimits(builder *openapi.DocBuilder) {
+	builder.
+		WithError(400, "invalid_content_encoding", "Failed to decode the gzip or deflate request body").
+		WithError(415, "unsupported_content_encoding", "The request body encoding is not gzip nor deflate")
+
+	if m.RequireContentLength {
+		builder.
+			WithParams(struct {
+				ContentLength int64 `header:"Content-Length" required:"true" description:"Size of the request body."`
+			}{}).
+			WithError(411, "length_required", "The Content-Length header is required")
+	}
+
+	if m.MaxBodySize > 0 {
+		builder.WithError(413, "body_too_large", fmt.Sprintf("The request body is larger than %d bytes", m.MaxBodySize))
+	}
+
+	if m.MaxDecompressionRatio > 0 {
+		builder.WithError(413, "decompression_ratio_exceeded", fmt.Sprintf("The decompressed request body is larger than %d times its compressed size", m.MaxDecompressionRatio))
+	}
+}
+
+// countingReader is a reader counting the bytes read.
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	return n, err
+}
+
+// decompressedBody is a decompressed request body, failing if its decompression ratio is exceeded.
+type decompressedBody struct {
+	decompressor io.ReadCloser
+	body         io.ReadCloser
+	compressed   *countingReader
+	read         int64
+	maxRatio     int64
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	n, err := b.decompressor.Read(p)
+	b.read += int64(n)
+
+	if b.maxRatio > 0 && b.read > minDecompressionRatioCheck && b.read > b.maxRatio*b.compressed.read {
+		return n, errDecompressionRatio{ratio: b.maxRatio}
+	}
+
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	_ = b.decompressor.Close()
+
+	return b.body.Close()
+}
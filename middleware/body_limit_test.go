@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/errors"
+)
+
+type limitedBody struct {
+	Name string `json:"name"`
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestBodyDecoder_limits(t *testing.T) {
+	serve := func(decoder BodyDecoder, body io.Reader, encoding string) error {
+		r := httptest.NewRequest(http.MethodPost, "/", body)
+		r.Header.Set("Content-Type", "application/json")
+		if encoding != "" {
+			r.Header.Set("Content-Encoding", encoding)
+		}
+
+		_, err := decoder.Wrap(noopHandler).Serve(nil, r)
+
+		return err
+	}
+
+	var body limitedBody
+	assert.NoError(t, serve(Body(&body), bytes.NewReader(gzipped(t, `{"name":"gopher"}`)), "gzip"))
+	assert.Equal(t, "gopher", body.Name)
+
+	err := serve(Body(&body).SetMaxBodySize(8), strings.NewReader(`{"name":"gopher"}`), "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(errors.Error).StatusCode())
+	assert.Equal(t, "body_too_large", err.(errors.Error).Kind())
+
+	// The decompressed size is limited too, even when the compressed body is small.
+	bomb := gzipped(t, `{"name":"`+strings.Repeat("a", 1<<20)+`"}`)
+	err = serve(Body(&body).SetMaxBodySize(1<<16).SetMaxDecompressionRatio(0), bytes.NewReader(bomb), "gzip")
+	assert.Equal(t, "body_too_large", err.(errors.Error).Kind())
+
+	err = serve(Body(&body), bytes.NewReader(bomb), "gzip")
+	assert.Equal(t, "decompression_ratio_exceeded", err.(errors.Error).Kind())
+
+	err = serve(Body(&body), strings.NewReader(`{}`), "br")
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(errors.Error).StatusCode())
+
+	// io.MultiReader hides the body size: the request has no Content-Length.
+	err = serve(Body(&body).SetRequireContentLength(true), io.MultiReader(strings.NewReader(`{}`)), "")
+	assert.Equal(t, http.StatusLengthRequired, err.(errors.Error).StatusCode())
+}
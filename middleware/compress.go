@@ -0,0 +1,450 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	gLog "github.com/mwm-io/gapi/log"
+)
+
+// Default values of Compress.
+const (
+	DefaultCompressMinSize = 1024
+	// CompressWeight is the weight of Compress: it is executed before ResponseWriter. (see handler.SortableMiddleware)
+	CompressWeight = -100
+)
+
+// Supported content encodings.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// DefaultSkippedContentTypes are the content types not compressed by default, because they are already compressed.
+var DefaultSkippedContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/zstd",
+	"application/pdf",
+}
+
+// Compress is a middleware compressing the responses with gzip or deflate, negotiated from the Accept-Encoding header
+// of the request with its q-values.
+//
+// It wraps the http.ResponseWriter, so it compresses everything written by ResponseWriter, including the streamed
+// io.Reader responses and the event streams: data is compressed as it is written and flushed with the response.
+// Its weight is CompressWeight, so it is executed before ResponseWriter even if it is added to the handler middlewares.
+//
+// The responses smaller than MinSize, with a skipped content type, already encoded (with a Content-Encoding header)
+// or partial (206 or with a Content-Range header) are not compressed.
+// A strong ETag of a compressed response is made weak, because the compressed body differs from the original one.
+type Compress struct {
+	// MinSize is the minimum size of a compressed body. Default to DefaultCompressMinSize.
+	MinSize int
+	// Level is the compression level. (see compress/flate) Default to flate.DefaultCompression if 0.
+	Level int
+	// Encodings are the supported encodings, by order of preference. Default to gzip, then deflate.
+	Encodings []string
+	// SkippedContentTypes are the prefixes of the content types not compressed. Default to DefaultSkippedContentTypes.
+	SkippedContentTypes []string
+}
+
+// MakeCompress returns an initialized Compress with the default values.
+func MakeCompress() Compress {
+	return Compress{
+		MinSize:             DefaultCompressMinSize,
+		Level:               gzip.DefaultCompression,
+		Encodings:           []string{EncodingGzip, EncodingDeflate},
+		SkippedContentTypes: DefaultSkippedContentTypes,
+	}
+}
+
+// Weight implements the handler.SortableMiddleware interface
+func (m Compress) Weight() int {
+	return CompressWeight
+}
+
+// Wrap implements the request.Middleware interface
+func (m Compress) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		AddVary(w.Header(), "Accept-Encoding")
+
+		encodings := m.Encodings
+		if len(encodings) == 0 {
+			encodings = []string{EncodingGzip, EncodingDeflate}
+		}
+
+		encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"), encodings...)
+		if encoding == "" || r.Method == http.MethodHead {
+			return h.Serve(w, r)
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			options:        m,
+			encoding:       encoding,
+		}
+		defer func() {
+			// The response is already written: the error can only be logged.
+			if err := cw.Close(); err != nil {
+				gLog.Error(r.Context()).LogError(errors.Wrap(err).WithKind("compress_failed"))
+			}
+		}()
+
+		return h.Serve(cw, r)
+	})
+}
+
+// NegotiateEncoding returns the supported encoding preferred by the Accept-Encoding header, using its q-values.
+// When several encodings have the same quality, the first supported one is returned.
+// It returns an empty string if no supported encoding is accepted.
+func NegotiateEncoding(acceptEncoding string, supported ...string) string {
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+
+		qualities[name] = quality
+	}
+
+	var (
+		best        string
+		bestQuality float64
+	)
+
+	for _, encoding := range supported {
+		quality, ok := qualities[strings.ToLower(encoding)]
+		if !ok {
+			quality = qualities["*"]
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	return best
+}
+
+// AddVary adds the given header name to the Vary header, if it isn't already there.
+func AddVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), name) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", name)
+}
+
+// compressWriter is a http.ResponseWriter compressing the body, once it knows the body must be compressed.
+//
+// The body is buffered until it reaches the minimum size, the response is flushed, or the handler returns.
+type compressWriter struct {
+	http.ResponseWriter
+	options  Compress
+	encoding string
+
+	statusCode int
+	buf        bytes.Buffer
+	decided    bool
+	compressor io.WriteCloser
+}
+
+// WriteHeader implements the http.ResponseWriter interface
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.statusCode != 0 || w.decided {
+		return
+	}
+
+	if statusCode >= 100 && statusCode <= 199 && statusCode != http.StatusSwitchingProtocols {
+		// Informational responses are sent before the final one. (ie: 103 Early Hints)
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.statusCode = statusCode
+	if !bodyAllowedForStatus(statusCode) {
+		_ = w.decide(false)
+	}
+}
+
+// Write implements the http.ResponseWriter interface
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	if w.decided {
+		if w.compressor != nil {
+			return w.compressor.Write(p)
+		}
+
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() >= w.minSize() {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush implements the http.Flusher interface
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.statusCode == 0 {
+			w.statusCode = http.StatusOK
+		}
+
+		// A flushed response is streamed: it is compressed whatever its size.
+		_ = w.decide(true)
+	}
+
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	w.decided = true
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter. (see http.ResponseController)
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close writes the buffered body and the end of the compressed stream.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if w.statusCode == 0 {
+			return nil
+		}
+
+		// The whole body is buffered: it is compressed only if it reached the minimum size.
+		if err := w.decide(w.buf.Len() >= w.minSize()); err != nil {
+			return err
+		}
+	}
+
+	if w.compressor == nil {
+		return nil
+	}
+
+	err := w.compressor.Close()
+	putCompressor(w.encoding, w.level(), w.compressor)
+	w.compressor = nil
+
+	return err
+}
+
+// decide writes the header, compressing the body if allowed, and writes the buffered body.
+func (w *compressWriter) decide(allowed bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if allowed && header.Get("Content-Type") == "" && w.buf.Len() > 0 {
+		// The content type must be detected before compressing the body, otherwise the compressed body is sniffed.
+		header.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+
+	if allowed && w.shouldCompress(header) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.compressor = getCompressor(w.encoding, w.level(), w.ResponseWriter)
+	}
+
+	if w.statusCode != 0 {
+		w.ResponseWriter.WriteHeader(w.statusCode)
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+
+	w.buf.Reset()
+
+	return err
+}
+
+// shouldCompress returns true if the response can be compressed.
+func (w *compressWriter) shouldCompress(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" || !bodyAllowedForStatus(w.statusCode) {
+		return false
+	}
+
+	// The ranges are computed on the original body.
+	if w.statusCode == http.StatusPartialContent || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < w.minSize() {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = header.Get("Content-Type")
+	}
+
+	skipped := w.options.SkippedContentTypes
+	if skipped == nil {
+		skipped = DefaultSkippedContentTypes
+	}
+
+	for _, prefix := range skipped {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *compressWriter) minSize() int {
+	if w.options.MinSize <= 0 {
+		return DefaultCompressMinSize
+	}
+
+	return w.options.MinSize
+}
+
+func (w *compressWriter) level() int {
+	if w.options.Level == 0 {
+		return gzip.DefaultCompression
+	}
+
+	return w.options.Level
+}
+
+// bodyAllowedForStatus returns true if a response with the given status can have a body.
+func bodyAllowedForStatus(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode <= 199:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
+	default:
+		return true
+	}
+}
+
+// compressorPools are the pools of compressors, by encoding and level.
+var compressorPools sync.Map
+
+type compressorPoolKey struct {
+	encoding string
+	level    int
+}
+
+// resettableCompressor is a compressor that can be reused for another writer.
+type resettableCompressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// getCompressor returns a compressor from the pool, writing into w.
+func getCompressor(encoding string, level int, w io.Writer) io.WriteCloser {
+	pool, _ := compressorPools.LoadOrStore(compressorPoolKey{encoding: encoding, level: level}, &sync.Pool{})
+
+	if compressor, ok := pool.(*sync.Pool).Get().(resettableCompressor); ok {
+		compressor.Reset(w)
+		return compressor
+	}
+
+	var (
+		compressor io.WriteCloser
+		err        error
+	)
+
+	switch encoding {
+	case EncodingDeflate:
+		compressor, err = zlib.NewWriterLevel(w, level)
+	default:
+		compressor, err = gzip.NewWriterLevel(w, level)
+	}
+
+	if err != nil {
+		// Invalid level: use the default one.
+		if encoding == EncodingDeflate {
+			return zlib.NewWriter(w)
+		}
+
+		return gzip.NewWriter(w)
+	}
+
+	return compressor
+}
+
+// putCompressor puts back a closed compressor in its pool.
+func putCompressor(encoding string, level int, compressor io.WriteCloser) {
+	if pool, ok := compressorPools.Load(compressorPoolKey{encoding: encoding, level: level}); ok {
+		pool.(*sync.Pool).Put(compressor)
+	}
+}
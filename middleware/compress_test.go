@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"gzip":                      EncodingGzip,
+		"deflate, gzip":             EncodingGzip,
+		"gzip;q=0.5, deflate":       EncodingDeflate,
+		"gzip;q=0, *":               EncodingDeflate,
+		"br, identity":              "",
+		"*":                         EncodingGzip,
+		"GZIP;q=0.8, deflate;q=0.2": EncodingGzip,
+	}
+
+	for acceptEncoding, expected := range tests {
+		assert.Equal(t, expected, NegotiateEncoding(acceptEncoding, EncodingGzip, EncodingDeflate), acceptEncoding)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	var resp interface{}
+	h := MakeCompress().Wrap(MakeResponseWriter().Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return resp, nil
+	})))
+
+	serve := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+
+		w := httptest.NewRecorder()
+		_, _ = h.Serve(w, r)
+
+		return w
+	}
+
+	large := strings.Repeat("gapi ", 1000)
+
+	resp = map[string]string{"text": large}
+	w := serve("gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	gzipReader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text":"`+large+`"}`, string(body))
+
+	resp = strings.NewReader(large)
+	w = serve("gzip;q=0.1, deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	zlibReader, err := zlib.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(zlibReader)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	resp = map[string]string{"text": "small"}
+	w = serve("gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.JSONEq(t, `{"text":"small"}`, w.Body.String())
+
+	resp = io.MultiReader(strings.NewReader("\x89PNG\r\n\x1a\n"), strings.NewReader(large))
+	w = serve("gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	partial := MakeCompress().Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		w.Header().Set("Content-Range", "bytes 0-4999/10000")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, large)
+
+		return nil, nil
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	_, _ = partial.Serve(w, r)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	tagged := MakeCompress().Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, large)
+
+		return nil, nil
+	}))
+
+	w = httptest.NewRecorder()
+	_, _ = tagged.Serve(w, r)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,349 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// Default values of ConcurrencyOptions.
+const (
+	DefaultConcurrencyRetryAfter   = time.Second
+	DefaultConcurrencyBackoffRatio = 0.9
+)
+
+// ConcurrencyOptions are the options of a ConcurrencyLimiter.
+type ConcurrencyOptions struct {
+	// MaxInFlight is the maximum number of requests served at the same time. If 0, there is no limit.
+	MaxInFlight int
+	// MaxQueue is the maximum number of requests waiting to be served. If 0, the requests over the limit are shed.
+	MaxQueue int
+	// QueueTimeout is the maximum duration a request waits to be served. If 0, it waits until its context is done.
+	QueueTimeout time.Duration
+	// RetryAfter is the duration the clients should wait after a shed request. Default to DefaultConcurrencyRetryAfter.
+	RetryAfter time.Duration
+
+	// Adaptive adjusts the limit to the observed latency, with AIMD (additive increase, multiplicative decrease):
+	// the limit is decreased when a request is slower than LatencyThreshold, or fails with a timeout,
+	// and it is increased again, up to MaxInFlight, while the requests are fast enough.
+	Adaptive bool
+	// LatencyThreshold is the latency from which the service is considered overloaded. (Adaptive only)
+	LatencyThreshold time.Duration
+	// MinInFlight is the minimum limit. (Adaptive only) Default to 1.
+	MinInFlight int
+	// BackoffRatio multiplies the limit when it is decreased. (Adaptive only) Default to DefaultConcurrencyBackoffRatio.
+	BackoffRatio float64
+}
+
+// ConcurrencyStats are the counters of a ConcurrencyLimiter, to be exposed as metrics.
+type ConcurrencyStats struct {
+	// Limit is the current limit: MaxInFlight, or the adjusted limit in Adaptive mode.
+	Limit int
+	// InFlight is the number of requests being served.
+	InFlight int
+	// Queued is the number of requests waiting to be served.
+	Queued int
+	// Served is the number of requests served since the limiter creation.
+	Served uint64
+	// Shed is the number of requests rejected since the limiter creation.
+	Shed uint64
+}
+
+// ConcurrencyLimiter limits the number of requests served at the same time.
+// Share a ConcurrencyLimiter between several ConcurrencyLimit middlewares to limit their requests together.
+type ConcurrencyLimiter struct {
+	options ConcurrencyOptions
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	queue    *list.List
+	served   uint64
+	shed     uint64
+}
+
+// NewConcurrencyLimiter returns a new ConcurrencyLimiter with the given options.
+func NewConcurrencyLimiter(options ConcurrencyOptions) *ConcurrencyLimiter {
+	if options.RetryAfter <= 0 {
+		options.RetryAfter = DefaultConcurrencyRetryAfter
+	}
+
+	if options.MinInFlight <= 0 {
+		options.MinInFlight = 1
+	}
+
+	if options.BackoffRatio <= 0 || options.BackoffRatio >= 1 {
+		options.BackoffRatio = DefaultConcurrencyBackoffRatio
+	}
+
+	return &ConcurrencyLimiter{
+		options: options,
+		limit:   float64(options.MaxInFlight),
+		queue:   list.New(),
+	}
+}
+
+// Acquire waits for the request to be allowed, and returns the function to call once it has been served,
+// with its latency and whether it has been dropped because of a timeout.
+// It returns errors.ServiceUnavailable if the request is shed.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(latency time.Duration, dropped bool), error) {
+	l.mu.Lock()
+
+	if l.inFlight < l.currentLimit() {
+		l.inFlight++
+		l.mu.Unlock()
+
+		return l.release, nil
+	}
+
+	if l.queue.Len() >= l.options.MaxQueue {
+		l.shed++
+		l.mu.Unlock()
+
+		return nil, l.shedError("too many requests in progress")
+	}
+
+	ready := make(chan struct{})
+	waiter := l.queue.PushBack(ready)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.options.QueueTimeout > 0 {
+		timer := time.NewTimer(l.options.QueueTimeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-ctx.Done():
+	case <-timeout:
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-ready:
+		// The slot has been given to the request while it stopped waiting.
+		return l.release, nil
+	default:
+	}
+
+	l.queue.Remove(waiter)
+	l.shed++
+
+	return nil, l.shedError("the request waited too long to be served")
+}
+
+// Stats returns the current counters of the limiter.
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return ConcurrencyStats{
+		Limit:    l.currentLimit(),
+		InFlight: l.inFlight,
+		Queued:   l.queue.Len(),
+		Served:   l.served,
+		Shed:     l.shed,
+	}
+}
+
+// release frees the slot of a served request, adjusts the limit, and gives the free slots to the waiting requests.
+func (l *ConcurrencyLimiter) release(latency time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.served++
+
+	if l.options.Adaptive {
+		l.adjust(latency, dropped)
+	}
+
+	for l.inFlight < l.currentLimit() && l.queue.Len() > 0 {
+		ready := l.queue.Remove(l.queue.Front()).(chan struct{})
+		l.inFlight++
+		close(ready)
+	}
+}
+
+// adjust updates the limit with AIMD: +1 per window of limit requests, or *BackoffRatio on overload.
+func (l *ConcurrencyLimiter) adjust(latency time.Duration, dropped bool) {
+	maxLimit := float64(l.options.MaxInFlight)
+	minLimit := math.Min(float64(l.options.MinInFlight), maxLimit)
+
+	if dropped || (l.options.LatencyThreshold > 0 && latency > l.options.LatencyThreshold) {
+		l.limit = math.Max(minLimit, l.limit*l.options.BackoffRatio)
+		return
+	}
+
+	l.limit = math.Min(maxLimit, l.limit+1/l.limit)
+}
+
+func (l *ConcurrencyLimiter) currentLimit() int {
+	return int(l.limit)
+}
+
+func (l *ConcurrencyLimiter) shedError(reason string) errors.Error {
+	return errors.ServiceUnavailable("overloaded", "%s, retry in %d seconds", reason, ceilSeconds(l.options.RetryAfter))
+}
+
+// ConcurrencyLimit is a middleware limiting the number of requests served at the same time,
+// to shed the excess load early instead of queuing requests until they time out.
+//
+// The requests over the limit wait in a bounded queue, then are shed with errors.ServiceUnavailable (503)
+// and a Retry-After header.
+//
+// Without Limiter, each route has its own limiter (per-route cap), created once by the first request of the route,
+// even for the handlers registered with a factory, wrapped for each request. (see OnRouteLimiter to read its counters)
+// Set the same Limiter to several middlewares to limit their requests together (global cap), and to read its counters.
+//
+//	limiter := middleware.NewConcurrencyLimiter(middleware.ConcurrencyOptions{MaxInFlight: 100, MaxQueue: 50})
+//	app.UseMiddlewares(middleware.ConcurrencyLimit{Limiter: limiter})
+type ConcurrencyLimit struct {
+	// Limiter limits the requests of the wrapped handlers.
+	// If nil, a ConcurrencyLimiter is created with Options for each route.
+	Limiter *ConcurrencyLimiter
+	// Options are the options of the limiters created when Limiter is nil.
+	Options ConcurrencyOptions
+	// OnRouteLimiter is called with each per-route limiter once it is created, to expose its counters as metrics.
+	// (see ConcurrencyLimiter.Stats) The route is nil for the requests not served by a mux.Router.
+	OnRouteLimiter func(route *mux.Route, limiter *ConcurrencyLimiter)
+}
+
+// routeLimiters are the limiters of the ConcurrencyLimit middlewares without Limiter, by route and options.
+var routeLimiters sync.Map
+
+type routeLimiterKey struct {
+	route   *mux.Route
+	options ConcurrencyOptions
+}
+
+// MakeConcurrencyLimit returns a ConcurrencyLimit serving at most maxInFlight requests at the same time per route,
+// with maxQueue waiting requests.
+func MakeConcurrencyLimit(maxInFlight, maxQueue int) ConcurrencyLimit {
+	return ConcurrencyLimit{
+		Options: ConcurrencyOptions{
+			MaxInFlight: maxInFlight,
+			MaxQueue:    maxQueue,
+			RetryAfter:  DefaultConcurrencyRetryAfter,
+		},
+	}
+}
+
+// SetQueueTimeout set Options.QueueTimeout and return current instance
+func (m ConcurrencyLimit) SetQueueTimeout(timeout time.Duration) ConcurrencyLimit {
+	m.Options.QueueTimeout = timeout
+	return m
+}
+
+// SetAdaptive enables the Adaptive mode with the given latency threshold and return current instance
+func (m ConcurrencyLimit) SetAdaptive(latencyThreshold time.Duration) ConcurrencyLimit {
+	m.Options.Adaptive = true
+	m.Options.LatencyThreshold = latencyThreshold
+	return m
+}
+
+// SetOnRouteLimiter set OnRouteLimiter and return current instance
+func (m ConcurrencyLimit) SetOnRouteLimiter(onRouteLimiter func(route *mux.Route, limiter *ConcurrencyLimiter)) ConcurrencyLimit {
+	m.OnRouteLimiter = onRouteLimiter
+	return m
+}
+
+// Wrap implements the request.Middleware interface
+func (m ConcurrencyLimit) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		limiter := m.Limiter
+		if limiter == nil {
+			limiter = m.routeLimiter(r)
+		}
+
+		if limiter.options.MaxInFlight <= 0 {
+			return h.Serve(w, r)
+		}
+
+		release, err := limiter.Acquire(r.Context())
+		if err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(limiter.options.RetryAfter)))
+
+			return nil, err
+		}
+
+		var dropped bool
+		start := time.Now()
+		defer func() {
+			// The slot is released even if the handler panics.
+			release(time.Since(start), dropped)
+		}()
+
+		resp, err := h.Serve(w, r)
+		dropped = isTimeoutError(r, err)
+
+		return resp, err
+	})
+}
+
+// routeLimiter returns the limiter of the route of the request, creating it if needed.
+func (m ConcurrencyLimit) routeLimiter(r *http.Request) *ConcurrencyLimiter {
+	route := mux.CurrentRoute(r)
+	key := routeLimiterKey{route: route, options: m.Options}
+	if limiter, ok := routeLimiters.Load(key); ok {
+		return limiter.(*ConcurrencyLimiter)
+	}
+
+	limiter, loaded := routeLimiters.LoadOrStore(key, NewConcurrencyLimiter(m.Options))
+	if !loaded && m.OnRouteLimiter != nil {
+		m.OnRouteLimiter(route, limiter.(*ConcurrencyLimiter))
+	}
+
+	return limiter.(*ConcurrencyLimiter)
+}
+
+// Doc implements the openapi.Documented interface
+func (m ConcurrencyLimit) Doc(builder *openapi.DocBuilder) error {
+	options := m.Options
+	if m.Limiter != nil {
+		options = m.Limiter.options
+	}
+
+	builder.WithError(
+		http.StatusServiceUnavailable,
+		"overloaded",
+		fmt.Sprintf("More than %d requests in progress", options.MaxInFlight),
+	)
+
+	docResponseHeaders(builder, http.StatusServiceUnavailable, map[string]string{
+		"Retry-After": "Number of seconds before retrying the request.",
+	})
+
+	return builder.Error()
+}
+
+// isTimeoutError returns true if the request failed because it was too slow.
+func isTimeoutError(r *http.Request, err error) bool {
+	if r.Context().Err() == context.DeadlineExceeded {
+		return true
+	}
+
+	castedErr, ok := err.(errors.Error)
+	if !ok {
+		return false
+	}
+
+	return castedErr.StatusCode() == http.StatusGatewayTimeout || castedErr.Kind() == "timeout"
+}
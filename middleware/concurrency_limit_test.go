@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyOptions{MaxInFlight: 1, MaxQueue: 1})
+	unblock := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	h := ConcurrencyLimit{Limiter: limiter}.Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		started <- struct{}{}
+		<-unblock
+
+		return nil, nil
+	}))
+
+	serve := func() (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		_, err := h.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return w, err
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := serve()
+			errs <- err
+		}()
+	}
+
+	<-started
+	assert.Eventually(t, func() bool { return limiter.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+	w, err := serve()
+	assert.Equal(t, http.StatusServiceUnavailable, err.(errors.Error).StatusCode())
+	assert.Equal(t, "overloaded", err.(errors.Error).Kind())
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, ConcurrencyStats{Limit: 1, InFlight: 1, Queued: 1, Shed: 1}, limiter.Stats())
+
+	close(unblock)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+	assert.Equal(t, ConcurrencyStats{Limit: 1, Served: 2, Shed: 1}, limiter.Stats())
+}
+
+func TestConcurrencyLimiter_adaptive(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyOptions{MaxInFlight: 10, Adaptive: true, LatencyThreshold: time.Second})
+
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background())
+		assert.NoError(t, err)
+		release(2*time.Second, false)
+	}
+
+	// 10 * 0.9^3 = 7.29
+	assert.Equal(t, 7, limiter.Stats().Limit)
+
+	for i := 0; i < 30; i++ {
+		release, err := limiter.Acquire(context.Background())
+		assert.NoError(t, err)
+		release(time.Millisecond, false)
+	}
+
+	assert.Equal(t, 10, limiter.Stats().Limit)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// CookieParameters is a middleware that will set the request cookies into the Parameters field.
+// Fields are bound using the `cookie` tag and the `required:"true"` tag will return an error if the cookie is missing.
+type CookieParameters struct {
+	Parameters interface{}
+}
+
+// Wrap implements the request.Middleware interface
+func (m CookieParameters) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if m.Parameters == nil {
+			return h.Serve(w, r)
+		}
+
+		if err := bindStrings(m.Parameters, "cookie", cookieValues(r)); err != nil {
+			return nil, err
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m CookieParameters) BindingTarget() interface{} {
+	return m.Parameters
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m CookieParameters) WithBindingTarget(target interface{}) handler.Middleware {
+	m.Parameters = target
+	return m
+}
+
+// Doc implements the openapi.Documented interface
+func (m CookieParameters) Doc(builder *openapi.DocBuilder) error {
+	if m.Parameters == nil {
+		return nil
+	}
+
+	builder.
+		WithParams(m.Parameters).
+		WithError(400, "invalid_param_type", "A cookie has an invalid type").
+		WithError(400, "missing_param", "A required cookie is missing")
+
+	return builder.Error()
+}
+
+// cookieValues returns a lookup function returning all the values of the request cookies with the given name.
+func cookieValues(r *http.Request) func(name string) []string {
+	return func(name string) []string {
+		var values []string
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == name {
+				values = append(values, cookie.Value)
+			}
+		}
+
+		return values
+	}
+}
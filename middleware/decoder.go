@@ -22,6 +22,29 @@ func (f FuncAsDecoder) Unmarshal(b []byte, v interface{}) error {
 var DecoderByContentType = map[string]Decoder{
 	"application/json": FuncAsDecoder(json.Unmarshal),
 	"application/xml":  FuncAsDecoder(xml.Unmarshal),
-	// TODO url encoded
-	// TODO Form data
+}
+
+// FormDecoderByContentType contain the built in form decoders.
+// They are added to DecoderByContentType by Body when the body has `formData` fields.
+var FormDecoderByContentType = map[string]Decoder{
+	"application/x-www-form-urlencoded": FuncAsDecoder(UnmarshalForm),
+	"multipart/form-data":               MultipartDecoder{},
+}
+
+// decodersFor returns the built in decoders able to decode a body into bodyPtr.
+func decodersFor(bodyPtr interface{}) map[string]Decoder {
+	if !hasTaggedFields(bodyPtr, "formData") {
+		return DecoderByContentType
+	}
+
+	decoders := make(map[string]Decoder, len(DecoderByContentType)+len(FormDecoderByContentType))
+	for contentType, decoder := range DecoderByContentType {
+		decoders[contentType] = decoder
+	}
+
+	for contentType, decoder := range FormDecoderByContentType {
+		decoders[contentType] = decoder
+	}
+
+	return decoders
 }
@@ -6,8 +6,14 @@ import (
 
 // Defaults includes all default middlewares.
 // You can update this list if you want to change middleware configs for all you handlers.
-var Defaults = []handler.Middleware{
-	MakeJSONResponseWriter(),
-	Log{},
-	Recover{},
+var Defaults = NewDefaults()
+
+// NewDefaults returns a new list of the default middlewares.
+// It can be used to get default middlewares that are not shared with Defaults. (see server.App)
+func NewDefaults() []handler.Middleware {
+	return []handler.Middleware{
+		MakeJSONResponseWriter(),
+		Log{},
+		Recover{},
+	}
 }
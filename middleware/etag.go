@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// WithETag is a response able to return its own ETag.
+// The ETag is quoted if it isn't already. (ie: `v42` becomes `"v42"`)
+type WithETag interface {
+	ETag() string
+}
+
+// WithLastModified is a response able to return its last modification time.
+type WithLastModified interface {
+	LastModified() time.Time
+}
+
+// ETag is a middleware answering conditional GET and HEAD requests.
+//
+// It sets the ETag header of the successful responses written by ResponseWriter: the ETag of the response
+// if it implements WithETag or Versioned, otherwise a strong ETag computed from the encoded body.
+// The Last-Modified header is set if the response implements WithLastModified.
+// If the request If-None-Match (or If-Modified-Since) header matches, a 304 Not Modified is written without body.
+//
+// It must be executed after ResponseWriter. (ie: in the handler middlewares)
+type ETag struct{}
+
+// Wrap implements the request.Middleware interface
+func (m ETag) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			addBeforeWriteHook(r, m.beforeWrite)
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// Doc implements the openapi.Documented interface
+func (m ETag) Doc(builder *openapi.DocBuilder) error {
+	builder.
+		WithParams(struct {
+			IfNoneMatch     string `header:"If-None-Match" description:"ETag of the version of the resource known by the client."`
+			IfModifiedSince string `header:"If-Modified-Since" description:"Date of the version of the resource known by the client."`
+		}{}).
+		WithResponse(nil, openapi.WithStatusCode(http.StatusNotModified), openapi.WithDescription("The resource has not been modified."))
+
+	return builder.Error()
+}
+
+func (m ETag) beforeWrite(w http.ResponseWriter, r *http.Request, statusCode int, resp interface{}, body []byte) bool {
+	if statusCode != http.StatusOK {
+		return true
+	}
+
+	var etag string
+	switch v := resp.(type) {
+	case WithETag:
+		etag = QuoteETag(v.ETag())
+	case Versioned:
+		etag = QuoteETag(v.Version())
+	}
+
+	if etag == "" {
+		etag = StrongETag(body)
+	}
+
+	w.Header().Set("ETag", etag)
+
+	var lastModified time.Time
+	if withLastModified, ok := resp.(WithLastModified); ok {
+		lastModified = withLastModified.LastModified()
+		if !lastModified.IsZero() {
+			w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if !NotModified(r, etag, lastModified) {
+		return true
+	}
+
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+
+	return false
+}
+
+// StrongETag returns a strong ETag computed from the given content.
+func StrongETag(content []byte) string {
+	hash := sha256.Sum256(content)
+
+	return `"` + base64.RawURLEncoding.EncodeToString(hash[:18]) + `"`
+}
+
+// QuoteETag returns the given ETag quoted, if it isn't already. An empty ETag stays empty.
+func QuoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+
+	return `"` + etag + `"`
+}
+
+// NotModified returns true if the client already has the current version of the resource,
+// from the If-None-Match header, or from the If-Modified-Since header when there is no If-None-Match header.
+// (RFC 9110, section 13.1.3)
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return MatchETag(ifNoneMatch, etag, false)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// MatchETag returns true if the comma-separated list of ETags of a conditional header contains the given ETag.
+// The strong comparison (If-Match, If-Range) never matches a weak ETag,
+// the weak comparison (If-None-Match) ignores the W/ prefix.
+// "*" matches any existing representation, even with a weak ETag.
+func MatchETag(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	weak := strings.HasPrefix(etag, "W/")
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strong && weak {
+			continue
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if strong {
+				continue
+			}
+
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
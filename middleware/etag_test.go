@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+type versionedDocument struct {
+	Name    string    `json:"name"`
+	Updated time.Time `json:"-"`
+}
+
+func (d versionedDocument) ETag() string {
+	return "v1"
+}
+
+func (d versionedDocument) LastModified() time.Time {
+	return d.Updated
+}
+
+func TestETag(t *testing.T) {
+	var resp interface{} = map[string]string{"name": "gopher"}
+	h := MakeResponseWriter().Wrap(ETag{}.Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return resp, nil
+	})))
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+
+		w := httptest.NewRecorder()
+		_, _ = h.Serve(w, r)
+
+		return w
+	}
+
+	w := serve("", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, StrongETag([]byte(`{"name":"gopher"}`)), etag)
+
+	w = serve("If-None-Match", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = serve("If-None-Match", `"other"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"gopher"}`, w.Body.String())
+
+	updated := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	resp = versionedDocument{Name: "gopher", Updated: updated}
+
+	w = serve("", "")
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Mon, 02 Jan 2023 03:04:05 GMT", w.Header().Get("Last-Modified"))
+
+	w = serve("If-None-Match", `W/"v1"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve("If-Modified-Since", "Mon, 02 Jan 2023 03:04:05 GMT")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve("If-Modified-Since", "Sun, 01 Jan 2023 03:04:05 GMT")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMatchETag(t *testing.T) {
+	assert.True(t, MatchETag("*", `W/"v1"`, true))
+	assert.True(t, MatchETag("*", `"v1"`, true))
+	assert.False(t, MatchETag("*", "", true))
+	assert.False(t, MatchETag(`W/"v1"`, `W/"v1"`, true))
+	assert.False(t, MatchETag(`"v1"`, `W/"v1"`, true))
+	assert.True(t, MatchETag(`W/"v1"`, `"v1"`, false))
+}
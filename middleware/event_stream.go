@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwm-io/gapi/errors"
+	gLog "github.com/mwm-io/gapi/log"
+	"github.com/mwm-io/gapi/response"
+)
+
+// writeEventStream writes the events of the given stream as Server-Sent Events, flushing after each of them,
+// until the stream ends or the client disconnects.
+func (m ResponseWriter) writeEventStream(w http.ResponseWriter, r *http.Request, stream response.EventStream) error {
+	_, encoder, err := m.resolveStreamEncoder(r)
+	if err != nil {
+		return err
+	}
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush()
+
+	ctx := r.Context()
+	events, errCh := stream.Channel(ctx)
+
+	var heartbeat <-chan time.Time
+	if interval := stream.Heartbeat(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-heartbeat:
+			if _, err := w.Write([]byte(":\n\n")); err != nil {
+				// The client is gone.
+				return nil
+			}
+
+			flush()
+
+		case event, ok := <-events:
+			if !ok {
+				return m.writeEventStreamError(w, r, encoder, errCh, flush)
+			}
+
+			body, err := formatEvent(event, encoder)
+			if err != nil {
+				// The response has already been started: the error can't be written, the stream is ended.
+				gLog.Error(ctx).LogError(err)
+				return nil
+			}
+
+			if _, err := w.Write(body); err != nil {
+				// The client is gone.
+				return nil
+			}
+
+			flush()
+		}
+	}
+}
+
+// writeEventStreamError sends the error returned by the stream iterator, if any, as an "error" event.
+func (m ResponseWriter) writeEventStreamError(w http.ResponseWriter, r *http.Request, encoder Encoder, errCh <-chan error, flush func()) error {
+	if errCh == nil {
+		return nil
+	}
+
+	var err error
+	select {
+	case err = <-errCh:
+	default:
+		return nil
+	}
+
+	gErr, ok := err.(errors.Error)
+	if !ok {
+		gErr = errors.Wrap(err)
+	}
+
+	body, errFormat := formatEvent(response.Event{Name: "error", Data: gErr}, encoder)
+	if errFormat != nil {
+		gLog.Error(r.Context()).LogError(errFormat)
+		return nil
+	}
+
+	if _, errW := w.Write(body); errW == nil {
+		flush()
+	}
+
+	return nil
+}
+
+// resolveStreamEncoder returns the content type and the Encoder used for the data of the events
+// and the websocket messages.
+// The Accept header of these requests doesn't negotiate the content of the messages (ie: text/event-stream),
+// so the ForcedContentType or the DefaultContentType is used, falling back on application/json.
+func (m ResponseWriter) resolveStreamEncoder(r *http.Request) (string, Encoder, error) {
+	for _, contentType := range []string{m.ForcedContentType, m.DefaultContentType, "application/json"} {
+		if contentType == "" {
+			continue
+		}
+
+		if encoder, ok := m.Encoders[contentType]; ok {
+			return contentType, encoder, nil
+		}
+	}
+
+	return m.resolveContentType(r)
+}
+
+// formatEvent returns the text/event-stream representation of the given event.
+// It fails if the id or the name of the event contains a line break, as it would end the field.
+func formatEvent(event response.Event, encoder Encoder) ([]byte, error) {
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Name, "\r\n") {
+		return nil, errors.Err("invalid_event", "the id and the name of an event can't contain line breaks: %q %q", event.ID, event.Name)
+	}
+
+	var buf bytes.Buffer
+
+	if event.ID != "" {
+		buf.WriteString("id: " + event.ID + "\n")
+	}
+
+	if event.Name != "" {
+		buf.WriteString("event: " + event.Name + "\n")
+	}
+
+	if event.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data []byte
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		var err error
+		data, err = encoder.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrap(err).WithKind("event_marshal_failed")
+		}
+	}
+
+	if event.Data != nil {
+		// The lines of the data can end with \r\n, \r or \n.
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			buf.WriteString("data: ")
+			buf.Write(line)
+			buf.WriteString("\n")
+		}
+	}
+
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
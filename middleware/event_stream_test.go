@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/response"
+)
+
+type tick struct {
+	Count int `json:"count"`
+}
+
+func TestResponseWriter_EventStream(t *testing.T) {
+	events := make(chan response.Event, 3)
+	events <- response.Event{ID: "1", Name: "tick", Data: tick{Count: 1}}
+	events <- response.Event{ID: "2", Data: "line 1\nline 2", Retry: 2 * time.Second}
+	events <- response.Event{}
+	close(events)
+
+	h := handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return response.NewEventStream(events), nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	_, err := MakeResponseWriter().Wrap(h).Serve(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+	assert.Equal(t,
+		"id: 1\nevent: tick\ndata: {\"count\":1}\n\n"+
+			"id: 2\nretry: 2000\ndata: line 1\ndata: line 2\n\n"+
+			"\n",
+		w.Body.String())
+}
+
+func TestResponseWriter_EventStreamIterator(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Last-Event-ID", "41")
+
+	h := handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		lastID := response.LastEventID(r)
+
+		return response.NewEventStreamFromIterator(func(ctx context.Context) (response.Event, error) {
+			if lastID == "42" {
+				return response.Event{}, errors.Err("stream_failed", "stream failed")
+			}
+
+			lastID = "42"
+			return response.Event{ID: lastID}, nil
+		}).SetHeartbeatInterval(-1), nil
+	})
+
+	w := httptest.NewRecorder()
+
+	_, err := MakeResponseWriter().Wrap(h).Serve(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"id: 42\n\n"+
+			"event: error\ndata: {\"message\":\"stream failed\",\"kind\":\"stream_failed\"}\n\n",
+		w.Body.String())
+}
+
+func TestResponseWriter_EventStreamDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	h := handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return response.EventStream{
+			Events:            make(chan response.Event),
+			HeartbeatInterval: 10 * time.Millisecond,
+		}, nil
+	})
+
+	done := make(chan struct{})
+	pr, pw := io.Pipe()
+	w := &pipeResponseWriter{ResponseRecorder: httptest.NewRecorder(), w: pw}
+
+	go func() {
+		defer close(done)
+		_, err := MakeResponseWriter().Wrap(h).Serve(w, r)
+		assert.NoError(t, err)
+	}()
+
+	buf := make([]byte, 3)
+	_, err := io.ReadFull(pr, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, ":\n\n", string(buf))
+
+	cancel()
+	go func() { _, _ = io.Copy(io.Discard, pr) }()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("event stream did not stop on client disconnect")
+	}
+}
+
+func TestResponseWriter_EventStreamInvalidEvent(t *testing.T) {
+	events := make(chan response.Event, 3)
+	events <- response.Event{Data: "line 1\rline 2\r\nline 3"}
+	events <- response.Event{ID: "1\nevent: injected"}
+	events <- response.Event{Data: "never sent"}
+	close(events)
+
+	h := handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return response.NewEventStream(events), nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	// The stream has already started: the invalid event ends it without writing an error.
+	_, err := MakeResponseWriter().Wrap(h).Serve(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "data: line 1\ndata: line 2\ndata: line 3\n\n", w.Body.String())
+}
+
+type pipeResponseWriter struct {
+	*httptest.ResponseRecorder
+	w io.Writer
+}
+
+func (p *pipeResponseWriter) Write(b []byte) (int, error) {
+	return p.w.Write(b)
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// DefaultMultipartMaxMemory is the default maximum size of a multipart/form-data request stored in memory.
+// Bigger parts are stored in temporary files on disk.
+const DefaultMultipartMaxMemory = 32 << 20
+
+// File is an uploaded file of a multipart/form-data request.
+// Use a *File or []*File field with a `formData` tag to receive uploaded files.
+//
+// It is an alias of multipart.FileHeader, so it is documented as a binary string.
+type File = multipart.FileHeader
+
+// RequestDecoder is a Decoder able to decode the request on its own.
+// It is used by BodyDecoder instead of Unmarshal when the decoder needs more than the body bytes,
+// like the content type parameters or a streamed body.
+type RequestDecoder interface {
+	Decoder
+	DecodeRequest(r *http.Request, v interface{}) error
+}
+
+// UnmarshalForm decodes an application/x-www-form-urlencoded body into v.
+// Fields are bound using the `formData` tag.
+func UnmarshalForm(b []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return errors.BadRequest("invalid_body_format", "failed to parse form").
+			WithError(err)
+	}
+
+	return bindForm(v, values, nil)
+}
+
+// MultipartDecoder is a RequestDecoder decoding multipart/form-data requests.
+// Fields are bound using the `formData` tag, uploaded files are bound to *File or []*File fields.
+type MultipartDecoder struct {
+	// MaxMemory is the maximum size of the request stored in memory. Bigger parts are stored in temporary files.
+	// If 0, DefaultMultipartMaxMemory is used.
+	MaxMemory int64
+	// MaxFileSize is the maximum size of each uploaded file. If 0, there is no limit.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of the whole request body. If 0, there is no limit.
+	MaxTotalSize int64
+	// AllowedMimeTypes is the list of content types allowed for uploaded files. (ie: image/png, image/*)
+	// If empty, all content types are allowed.
+	AllowedMimeTypes []string
+}
+
+// Unmarshal implements the Decoder interface.
+// A multipart body can't be decoded without its boundary: use DecodeRequest instead.
+func (d MultipartDecoder) Unmarshal(_ []byte, _ interface{}) error {
+	return errors.InternalServerError("unsupported_decoder", "multipart/form-data can only be decoded from the request")
+}
+
+// DecodeRequest implements the RequestDecoder interface.
+func (d MultipartDecoder) DecodeRequest(r *http.Request, v interface{}) error {
+	if d.MaxTotalSize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, d.MaxTotalSize)
+	}
+
+	maxMemory := d.MaxMemory
+	if maxMemory == 0 {
+		maxMemory = DefaultMultipartMaxMemory
+	}
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		// The body can be limited by MaxTotalSize or by the BodyDecoder.
+		if castedErr := bodyReadError(err); castedErr.StatusCode() == http.StatusRequestEntityTooLarge {
+			return castedErr
+		}
+
+		return errors.BadRequest("invalid_body_format", "failed to parse multipart form").
+			WithError(err)
+	}
+
+	for _, files := range r.MultipartForm.File {
+		for _, file := range files {
+			if err := d.validateFile(file); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bindForm(v, r.MultipartForm.Value, r.MultipartForm.File)
+}
+
+func (d MultipartDecoder) validateFile(file *File) errors.Error {
+	if d.MaxFileSize > 0 && file.Size > d.MaxFileSize {
+		return errors.RequestEntityTooLarge("file_too_large", "file %s must not exceed %d bytes", file.Filename, d.MaxFileSize)
+	}
+
+	if len(d.AllowedMimeTypes) == 0 {
+		return nil
+	}
+
+	contentType := file.Header.Get("Content-Type")
+	for _, allowed := range d.AllowedMimeTypes {
+		if matched, _ := path.Match(allowed, contentType); matched {
+			return nil
+		}
+	}
+
+	return errors.UnsupportedMediaType("unsupported_file_type", "file %s has an unsupported content type %s", file.Filename, contentType)
+}
+
+// Doc implements the openapi.Documented interface
+func (d MultipartDecoder) Doc(builder *openapi.DocBuilder) error {
+	builder.WithError(400, "invalid_body_format", "Failed to decode request body")
+
+	if d.MaxTotalSize > 0 {
+		builder.WithError(413, "body_too_large", "The request body is too large")
+	}
+
+	if d.MaxFileSize > 0 {
+		builder.WithError(413, "file_too_large", "An uploaded file is too large")
+	}
+
+	if len(d.AllowedMimeTypes) != 0 {
+		builder.WithError(415, "unsupported_file_type", "An uploaded file has an unsupported content type")
+	}
+
+	return builder.Error()
+}
+
+var (
+	fileType      = reflect.TypeOf((*File)(nil))
+	fileSliceType = reflect.TypeOf([]*File(nil))
+)
+
+// bindForm sets every field of the struct pointed by ptr holding a `formData` tag
+// with the given form values and files.
+func bindForm(ptr interface{}, values url.Values, files map[string][]*File) errors.Error {
+	v := reflect.Indirect(reflect.ValueOf(ptr))
+	if v.Kind() != reflect.Struct {
+		// Only the structs can be decoded from a form.
+		return errors.UnsupportedMediaType("unsupported_content_type", "a form can't be decoded into this body")
+	}
+
+	typeOfParameters := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		fieldType := typeOfParameters.Field(i)
+		name := tagName(fieldType, "formData")
+		if name == "" || !v.Field(i).CanSet() {
+			continue
+		}
+
+		switch fieldType.Type {
+		case fileType:
+			if len(files[name]) != 0 {
+				v.Field(i).Set(reflect.ValueOf(files[name][0]))
+				continue
+			}
+
+		case fileSliceType:
+			if len(files[name]) != 0 {
+				v.Field(i).Set(reflect.ValueOf(files[name]))
+				continue
+			}
+
+		default:
+			if len(values[name]) != 0 {
+				if err := setFieldFromStrings(v.Field(i), name, values[name]); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if isRequired(fieldType) {
+			return errors.BadRequest("missing_param", "field %s is required", name)
+		}
+	}
+
+	return nil
+}
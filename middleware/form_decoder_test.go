@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+type uploadBody struct {
+	Title  string  `formData:"title" required:"true"`
+	Count  int     `formData:"count"`
+	Avatar *File   `formData:"avatar" required:"true"`
+	Files  []*File `formData:"files"`
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		assert.NoError(t, writer.WriteField(name, value))
+	}
+
+	for name, contentType := range files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="`+name+`"; filename="`+name+`.bin"`)
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		assert.NoError(t, err)
+		_, _ = part.Write([]byte("0123456789"))
+	}
+
+	assert.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return r
+}
+
+func TestBodyDecoder_form(t *testing.T) {
+	var body uploadBody
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("title=hello&count=3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	_, err := Body(&body).Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "missing_param", err.(errors.Error).Kind())
+	assert.Equal(t, "hello", body.Title)
+	assert.Equal(t, 3, body.Count)
+}
+
+func TestBodyDecoder_multipart(t *testing.T) {
+	var body uploadBody
+
+	r := newMultipartRequest(t, map[string]string{"title": "hello"}, map[string]string{"avatar": "image/png"})
+	_, err := Body(&body).Wrap(noopHandler).Serve(nil, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", body.Title)
+	assert.Equal(t, "avatar.bin", body.Avatar.Filename)
+	assert.Equal(t, int64(10), body.Avatar.Size)
+
+	decoder := Body(&uploadBody{}).SetDecoders(map[string]Decoder{
+		"multipart/form-data": MultipartDecoder{MaxFileSize: 5},
+	})
+	r = newMultipartRequest(t, map[string]string{"title": "hello"}, map[string]string{"avatar": "image/png"})
+	_, err = decoder.Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "file_too_large", err.(errors.Error).Kind())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(errors.Error).StatusCode())
+
+	decoder = Body(&uploadBody{}).SetDecoders(map[string]Decoder{
+		"multipart/form-data": MultipartDecoder{AllowedMimeTypes: []string{"image/*"}},
+	})
+	r = newMultipartRequest(t, map[string]string{"title": "hello"}, map[string]string{"avatar": "application/pdf"})
+	_, err = decoder.Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "unsupported_file_type", err.(errors.Error).Kind())
+
+	decoder = Body(&uploadBody{}).SetDecoders(map[string]Decoder{
+		"multipart/form-data": MultipartDecoder{MaxTotalSize: 64},
+	})
+	r = newMultipartRequest(t, map[string]string{"title": "hello"}, map[string]string{"avatar": "image/png"})
+	_, err = decoder.Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "body_too_large", err.(errors.Error).Kind())
+}
+
+func TestBodyDecoder_multipartDoc(t *testing.T) {
+	builder := openapi.NewDocBuilder(new(openapi3.Reflector), http.MethodPost, "/")
+
+	assert.NoError(t, Body(&uploadBody{}).Doc(builder))
+
+	content := builder.Operation().RequestBody.RequestBody.Content
+	assert.Contains(t, content, "multipart/form-data")
+
+	schemas := builder.Reflector().Spec.Components.Schemas.MapOfSchemaOrRefValues
+	assert.Equal(t, []string{"title", "avatar"}, schemas["FormDataMiddlewareUploadBody"].Schema.Required)
+	assert.Equal(t, "binary", *schemas["FormDataMultipartFileHeader"].Schema.Format)
+}
+
+func TestBodyDecoder_formNotStruct(t *testing.T) {
+	var body []string
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("title=hello"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// The form decoders are only registered for the bodies with `formData` fields.
+	_, err := Body(&body).Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "invalid_content_type", err.(errors.Error).Kind())
+
+	decoders := map[string]Decoder{"application/x-www-form-urlencoded": FuncAsDecoder(UnmarshalForm)}
+	_, err = Body(&body).SetDecoders(decoders).Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(errors.Error).StatusCode())
+}
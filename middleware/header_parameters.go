@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// HeaderParameters is a middleware that will set the request headers into the Parameters field.
+// Fields are bound using the `header` tag and the `required:"true"` tag will return an error if the header is missing.
+type HeaderParameters struct {
+	Parameters interface{}
+}
+
+// Wrap implements the request.Middleware interface
+func (m HeaderParameters) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if m.Parameters == nil {
+			return h.Serve(w, r)
+		}
+
+		if err := bindStrings(m.Parameters, "header", r.Header.Values); err != nil {
+			return nil, err
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m HeaderParameters) BindingTarget() interface{} {
+	return m.Parameters
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m HeaderParameters) WithBindingTarget(target interface{}) handler.Middleware {
+	m.Parameters = target
+	return m
+}
+
+// Doc implements the openapi.Documented interface
+func (m HeaderParameters) Doc(builder *openapi.DocBuilder) error {
+	if m.Parameters == nil {
+		return nil
+	}
+
+	builder.
+		WithParams(m.Parameters).
+		WithError(400, "invalid_param_type", "A header has an invalid type").
+		WithError(400, "missing_param", "A required header is missing")
+
+	return builder.Error()
+}
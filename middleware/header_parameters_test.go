@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+type headerParams struct {
+	TenantID  string   `header:"X-Tenant-ID" required:"true"`
+	Version   int      `header:"X-Version"`
+	Languages []string `header:"Accept-Language"`
+}
+
+type cookieParams struct {
+	Session string `cookie:"session" required:"true"`
+	Visits  *int   `cookie:"visits"`
+}
+
+var noopHandler = handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return nil, nil
+})
+
+func TestHeaderParameters_Wrap(t *testing.T) {
+	var params headerParams
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant-ID", "mwm")
+	r.Header.Set("X-Version", "2")
+	r.Header.Add("Accept-Language", "fr")
+	r.Header.Add("Accept-Language", "en")
+
+	_, err := HeaderParameters{Parameters: &params}.Wrap(noopHandler).Serve(nil, r)
+	assert.NoError(t, err)
+	assert.Equal(t, headerParams{TenantID: "mwm", Version: 2, Languages: []string{"fr", "en"}}, params)
+
+	r.Header.Del("X-Tenant-ID")
+	_, err = HeaderParameters{Parameters: &headerParams{}}.Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, "missing_param", err.(errors.Error).Kind())
+
+	r.Header.Set("X-Tenant-ID", "mwm")
+	r.Header.Set("X-Version", "two")
+	_, err = HeaderParameters{Parameters: &headerParams{}}.Wrap(noopHandler).Serve(nil, r)
+	assert.Equal(t, http.StatusBadRequest, err.(errors.Error).StatusCode())
+	assert.Equal(t, "invalid_param_type", err.(errors.Error).Kind())
+}
+
+func TestCookieParameters_Wrap(t *testing.T) {
+	var params cookieParams
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	r.AddCookie(&http.Cookie{Name: "visits", Value: "3"})
+
+	_, err := CookieParameters{Parameters: &params}.Wrap(noopHandler).Serve(nil, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", params.Session)
+	assert.Equal(t, 3, *params.Visits)
+
+	_, err = CookieParameters{Parameters: &cookieParams{}}.Wrap(noopHandler).Serve(nil, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "missing_param", err.(errors.Error).Kind())
+}
+
+func TestHeaderAndCookieParameters_Doc(t *testing.T) {
+	builder := openapi.NewDocBuilder(new(openapi3.Reflector), http.MethodGet, "/")
+
+	assert.NoError(t, HeaderParameters{Parameters: &headerParams{}}.Doc(builder))
+	assert.NoError(t, CookieParameters{Parameters: &cookieParams{}}.Doc(builder))
+
+	params := map[string]openapi3.ParameterIn{}
+	for _, param := range builder.Operation().Parameters {
+		params[param.Parameter.Name] = param.Parameter.In
+	}
+
+	assert.Equal(t, map[string]openapi3.ParameterIn{
+		"X-Tenant-ID":     openapi3.ParameterInHeader,
+		"X-Version":       openapi3.ParameterInHeader,
+		"Accept-Language": openapi3.ParameterInHeader,
+		"session":         openapi3.ParameterInCookie,
+		"visits":          openapi3.ParameterInCookie,
+	}, params)
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// Versioned is a resource with a version, changing each time the resource is updated.
+//
+// When a response implements Versioned, ResponseWriter sets its ETag header to the quoted version,
+// so that clients can send it back in the If-Match header of their updates. (see IfMatch)
+type Versioned interface {
+	Version() string
+}
+
+// IfMatch is a middleware protecting the updates (PUT, PATCH, DELETE...) against lost updates, with optimistic concurrency.
+//
+// The client sends the ETag of the version it updates in the If-Match header: the request is rejected with
+// errors.PreconditionFailed (412) if the resource has been updated since.
+//
+//	middleware.IfMatch{
+//		Required: true,
+//		Current: func(r *http.Request) (middleware.Versioned, error) {
+//			return store.Get(r.Context(), mux.Vars(r)["id"])
+//		},
+//	}
+//
+// If Current is nil, the handler must check the version itself once it loaded the resource. (see CheckIfMatch)
+type IfMatch struct {
+	// Required rejects the requests without If-Match header with errors.PreconditionRequired (428).
+	Required bool
+	// Current returns the current version of the resource targeted by the request.
+	Current func(r *http.Request) (Versioned, error)
+}
+
+// Wrap implements the request.Middleware interface
+func (m IfMatch) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if isSafeMethod(r.Method) {
+			return h.Serve(w, r)
+		}
+
+		if r.Header.Get("If-Match") == "" {
+			if m.Required {
+				return nil, errors.PreconditionRequired("precondition_required", "the If-Match header is required to update this resource")
+			}
+
+			return h.Serve(w, r)
+		}
+
+		if m.Current != nil {
+			current, err := m.Current(r)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := CheckIfMatch(r, current); err != nil {
+				return nil, err
+			}
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// Doc implements the openapi.Documented interface
+func (m IfMatch) Doc(builder *openapi.DocBuilder) error {
+	if m.Required {
+		builder.
+			WithParams(struct {
+				IfMatch string `header:"If-Match" required:"true" description:"ETag of the version of the resource to update."`
+			}{}).
+			WithError(http.StatusPreconditionRequired, "precondition_required", "the If-Match header is required to update this resource")
+	} else {
+		builder.WithParams(struct {
+			IfMatch string `header:"If-Match" description:"ETag of the version of the resource to update."`
+		}{})
+	}
+
+	builder.WithError(http.StatusPreconditionFailed, "precondition_failed", "the resource has been modified since its version was fetched")
+
+	return builder.Error()
+}
+
+// CheckIfMatch returns errors.PreconditionFailed if the If-Match header of the request doesn't match the current
+// version of the resource. It returns nil if the request has no If-Match header.
+func CheckIfMatch(r *http.Request, current Versioned) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+
+	var etag string
+	if current != nil {
+		etag = QuoteETag(current.Version())
+	}
+
+	if !MatchETag(ifMatch, etag, true) {
+		return errors.PreconditionFailed("precondition_failed", "the resource has been modified since its version was fetched")
+	}
+
+	return nil
+}
+
+// isSafeMethod returns true if the method doesn't modify resources. (RFC 9110, section 9.2.1)
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+type article struct {
+	Title    string `json:"title"`
+	Revision string `json:"-"`
+}
+
+func (a article) Version() string {
+	return a.Revision
+}
+
+func TestIfMatch(t *testing.T) {
+	current := article{Title: "gapi", Revision: "3"}
+
+	h := MakeResponseWriter().Wrap(IfMatch{
+		Required: true,
+		Current: func(r *http.Request) (Versioned, error) {
+			return current, nil
+		},
+	}.Wrap(handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return article{Title: "gapi", Revision: "4"}, nil
+	})))
+
+	serve := func(method, ifMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		_, _ = h.Serve(w, r)
+
+		return w
+	}
+
+	w := serve(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	w = serve(http.MethodPut, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.JSONEq(t, `{"kind":"precondition_required","message":"the If-Match header is required to update this resource"}`, w.Body.String())
+
+	w = serve(http.MethodPut, `"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = serve(http.MethodPut, `W/"3"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = serve(http.MethodPut, `"2", "3"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/mwm-io/gapi/errors"
 	"github.com/mwm-io/gapi/handler"
 	gLog "github.com/mwm-io/gapi/log"
 )
@@ -12,7 +14,17 @@ import (
 // Log is a middleware that will:
 // - set the given logger into the request's context.
 // - log any error returned by the next handler
-type Log struct{}
+type Log struct {
+	// LevelByStatus returns the level of the errors with the given status code. (ie: zapcore.WarnLevel for 429)
+	// If nil, the errors are logged at error level. The errors logged below error level have no stacktrace.
+	LevelByStatus func(statusCode int) zapcore.Level
+}
+
+// SetLevelByStatus set LevelByStatus and return current instance
+func (m Log) SetLevelByStatus(levelByStatus func(statusCode int) zapcore.Level) Log {
+	m.LevelByStatus = levelByStatus
+	return m
+}
 
 // Wrap implements the request.Middleware interface
 func (m Log) Wrap(h handler.Handler) handler.Handler {
@@ -26,12 +38,36 @@ func (m Log) Wrap(h handler.Handler) handler.Handler {
 		resp, err := h.Serve(w, r)
 
 		if err != nil {
-			latest := gLog.LatestLogger(r.Context())
-			errLog := &gLog.Log{}
-			errLog.SetFunc(latest.WithOptions(zap.AddCallerSkip(1)).Error)
-			errLog.LogError(err)
+			m.logError(r, err)
 		}
 
 		return resp, err
 	})
 }
+
+// logError logs the error returned by the next handler, at the level of its status code.
+func (m Log) logError(r *http.Request, err error) {
+	latest := gLog.LatestLogger(r.Context())
+
+	level := zapcore.ErrorLevel
+	castedErr, ok := err.(errors.Error)
+	if ok && m.LevelByStatus != nil {
+		level = m.LevelByStatus(castedErr.StatusCode())
+	}
+
+	if ok && level < zapcore.ErrorLevel {
+		latest.WithOptions(zap.AddCallerSkip(1)).Log(level, castedErr.Error(),
+			zap.String("kind", castedErr.Kind()),
+			zap.Int("status_code", castedErr.StatusCode()),
+		)
+
+		return
+	}
+
+	latest = latest.WithOptions(zap.AddCallerSkip(2))
+	errLog := &gLog.Log{}
+	errLog.SetFunc(func(msg string, fields ...zap.Field) {
+		latest.Log(level, msg, fields...)
+	})
+	errLog.LogError(err)
+}
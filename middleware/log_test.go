@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	gLog "github.com/mwm-io/gapi/log"
+)
+
+func TestLog_LevelByStatus(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	serve := func(m Log, err error) {
+		h := m.Wrap(handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+			return nil, err
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(gLog.NewContext(r.Context(), zap.New(core)))
+
+		_, _ = h.Serve(httptest.NewRecorder(), r)
+	}
+
+	tooManyRequests := errors.TooManyRequests("rate_limited", "too many requests")
+
+	serve(Log{}, tooManyRequests)
+	serve(Log{}.SetLevelByStatus(func(statusCode int) zapcore.Level {
+		if statusCode == http.StatusTooManyRequests {
+			return zapcore.WarnLevel
+		}
+
+		return zapcore.ErrorLevel
+	}), tooManyRequests)
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+		assert.Contains(t, entries[0].ContextMap(), "stacktrace")
+
+		assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+		assert.Equal(t, "rate_limited", entries[1].ContextMap()["kind"])
+		assert.NotContains(t, entries[1].ContextMap(), "stacktrace")
+	}
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mwm-io/gapi/errors"
+)
+
+// setFieldFromStrings converts the given raw values into the type of field and sets it.
+// Slices are filled with all values, other kinds only use the first one.
+func setFieldFromStrings(field reflect.Value, name string, values []string) errors.Error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFieldFromStrings(ptr.Elem(), name, values); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setFieldFromString(slice.Index(i), name, val); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	return setFieldFromString(field, name, values[0])
+}
+
+// setFieldFromString converts the given raw value into the type of field and sets it.
+func setFieldFromString(field reflect.Value, name, val string) errors.Error {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(val, 10, field.Type().Bits())
+		if err != nil {
+			return errors.BadRequest("invalid_param_type", "%s must be a number", name).
+				WithError(err)
+		}
+		field.SetInt(x)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := strconv.ParseUint(val, 10, field.Type().Bits())
+		if err != nil {
+			return errors.BadRequest("invalid_param_type", "%s must be a positive number", name).
+				WithError(err)
+		}
+		field.SetUint(x)
+
+	case reflect.Float64, reflect.Float32:
+		x, err := strconv.ParseFloat(val, field.Type().Bits())
+		if err != nil {
+			return errors.BadRequest("invalid_param_type", "%s must be a float", name).
+				WithError(err)
+		}
+		field.SetFloat(x)
+
+	case reflect.Bool:
+		x, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.BadRequest("invalid_param_type", "%s must be a boolean", name).
+				WithError(err)
+		}
+		field.SetBool(x)
+
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			return errors.InternalServerError("invalid_param_type", "type %q isn't supported. Caused by parameter '%s'", field.Type().String(), name)
+		}
+		field.SetBytes([]byte(val))
+
+	case reflect.String:
+		field.SetString(val)
+
+	default:
+		return errors.InternalServerError("invalid_param_type", "type %q isn't supported. Caused by parameter '%s'", field.Kind().String(), name)
+	}
+
+	return nil
+}
+
+// tagName returns the name part of a struct tag value. (ie: `header:"X-Tenant-ID,omitempty"` returns X-Tenant-ID)
+func tagName(field reflect.StructField, tag string) string {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok || value == "-" {
+		return ""
+	}
+
+	return strings.Split(value, ",")[0]
+}
+
+// isRequired returns true if the struct field has a `required:"true"` tag.
+func isRequired(field reflect.StructField) bool {
+	return field.Tag.Get("required") == "true"
+}
+
+// bindStrings sets every field of the struct pointed by ptr holding the given tag
+// with the values returned by lookup.
+func bindStrings(ptr interface{}, tag string, lookup func(name string) []string) errors.Error {
+	v := reflect.Indirect(reflect.ValueOf(ptr))
+	if v.Kind() != reflect.Struct {
+		return errors.InternalServerError("invalid_param_receiver", "%s parameters must be a pointer to a struct", tag)
+	}
+
+	typeOfParameters := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		fieldType := typeOfParameters.Field(i)
+		name := tagName(fieldType, tag)
+		if name == "" || !v.Field(i).CanSet() {
+			continue
+		}
+
+		values := lookup(name)
+		if len(values) == 0 {
+			if isRequired(fieldType) {
+				return errors.BadRequest("missing_param", "%s %s is required", tag, name)
+			}
+
+			continue
+		}
+
+		if err := setFieldFromStrings(v.Field(i), name, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isBodyField returns true if the struct field is set from the request body, with a `json` or `formData` tag.
+func isBodyField(field reflect.StructField) bool {
+	for _, tag := range []string{"json", "formData"} {
+		if value, ok := field.Tag.Lookup(tag); ok && value != "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parameterTags are the tags of the fields set from the path, query, headers and cookies of the request.
+var parameterTags = []string{"path", "query", "header", "cookie"}
+
+// parameterTag returns the tag and the name of the request parameter setting the struct field, if any.
+func parameterTag(field reflect.StructField) (string, string) {
+	for _, tag := range parameterTags {
+		if name := tagName(field, tag); name != "" {
+			return tag, name
+		}
+	}
+
+	return "", ""
+}
+
+// resetNonBodyFields sets to their zero value the path, query, header and cookie fields of the struct pointed by ptr
+// that aren't body fields. The body is decoded into the whole struct, so it could set the parameters
+// missing from the request.
+func resetNonBodyFields(ptr interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(ptr))
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if _, name := parameterTag(field); name == "" || isBodyField(field) || !v.Field(i).CanSet() {
+			continue
+		}
+
+		v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+	}
+}
+
+// hasTaggedFields returns true if the struct pointed by ptr has at least one field with the given tag.
+func hasTaggedFields(ptr interface{}, tag string) bool {
+	t := reflect.TypeOf(ptr)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if tagName(t.Field(i), tag) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasBody returns true if the request carries a body.
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
@@ -73,6 +73,17 @@ func (m PathParameters) Wrap(h handler.Handler) handler.Handler {
 	})
 }
 
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m PathParameters) BindingTarget() interface{} {
+	return m.Parameters
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m PathParameters) WithBindingTarget(target interface{}) handler.Middleware {
+	m.Parameters = target
+	return m
+}
+
 // Doc implements the openapi.Documented interface
 func (m PathParameters) Doc(builder *openapi.DocBuilder) error {
 	if m.Parameters == nil {
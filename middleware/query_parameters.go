@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gorilla/schema"
@@ -10,11 +11,34 @@ import (
 	"github.com/mwm-io/gapi/openapi"
 )
 
-var decoder = schema.NewDecoder()
+// queryDecoder is the decoder used by QueryParameters when the request doesn't carry one. (see WithQueryDecoder)
+// It is configured once and must not be modified afterwards, as it is shared by all requests.
+var queryDecoder = NewQueryDecoder()
+
+type queryDecoderKey struct{}
+
+// WithQueryDecoder returns a new Context carrying the decoder used by QueryParameters to decode the query parameters.
+// It is set by server.App, so that each App uses its own decoder.
+func WithQueryDecoder(ctx context.Context, decoder *schema.Decoder) context.Context {
+	return context.WithValue(ctx, queryDecoderKey{}, decoder)
+}
+
+// NewQueryDecoder returns a new schema.Decoder decoding the `query` tags and ignoring unknown keys.
+func NewQueryDecoder() *schema.Decoder {
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	decoder.SetAliasTag("query")
+
+	return decoder
+}
 
 // QueryParameters is a middleware that will set the request query parameters into the Parameters field.
 type QueryParameters struct {
 	Parameters interface{}
+	// Decoder is the decoder used to decode the query parameters.
+	// If nil, the decoder carried by the request context is used (see WithQueryDecoder),
+	// otherwise a default decoder built with NewQueryDecoder.
+	Decoder *schema.Decoder
 }
 
 // Wrap implements the request.Middleware interface
@@ -24,8 +48,15 @@ func (m QueryParameters) Wrap(h handler.Handler) handler.Handler {
 			return h.Serve(w, r)
 		}
 
-		decoder.IgnoreUnknownKeys(true)
-		decoder.SetAliasTag("query")
+		decoder := m.Decoder
+		if decoder == nil {
+			decoder, _ = r.Context().Value(queryDecoderKey{}).(*schema.Decoder)
+		}
+
+		if decoder == nil {
+			decoder = queryDecoder
+		}
+
 		err := decoder.Decode(m.Parameters, r.URL.Query())
 		if err != nil {
 			return nil, errors.UnprocessableEntity("query_params_encoding", "failed to decode query params").
@@ -36,6 +67,17 @@ func (m QueryParameters) Wrap(h handler.Handler) handler.Handler {
 	})
 }
 
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m QueryParameters) BindingTarget() interface{} {
+	return m.Parameters
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m QueryParameters) WithBindingTarget(target interface{}) handler.Middleware {
+	m.Parameters = target
+	return m
+}
+
 // Doc implements the openapi.Documented interface
 func (m QueryParameters) Doc(builder *openapi.DocBuilder) error {
 	if m.Parameters == nil {
@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/swaggest/openapi-go/openapi3"
+	"go.uber.org/zap"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	gLog "github.com/mwm-io/gapi/log"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// RateLimitKey returns the key of the client sending the request: the requests with the same key share the same quota.
+// The requests with an empty key are not limited.
+type RateLimitKey func(r *http.Request) string
+
+// KeyByIP is a RateLimitKey limiting the requests by client IP, from the request RemoteAddr.
+// Behind a proxy, the RemoteAddr is the address of the proxy: use a custom RateLimitKey reading the header set by the proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// KeyByHeader returns a RateLimitKey limiting the requests by the value of the given header. (ie: an API key)
+// The requests without this header are not limited.
+func KeyByHeader(name string) RateLimitKey {
+	return func(r *http.Request) string {
+		value := r.Header.Get(name)
+		if value == "" {
+			return ""
+		}
+
+		return "header:" + name + ":" + value
+	}
+}
+
+// KeyByPrincipal is a RateLimitKey limiting the requests by authenticated principal. (see WithPrincipal)
+// The requests without principal are not limited.
+func KeyByPrincipal(r *http.Request) string {
+	principal, ok := Principal(r.Context())
+	if !ok || principal == "" {
+		return ""
+	}
+
+	return "principal:" + principal
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a new Context carrying the identifier of the authenticated principal of the request.
+// It is set by the authentication middlewares, and read by KeyByPrincipal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the identifier of the authenticated principal carried by the given Context.
+func Principal(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+
+	return principal, ok
+}
+
+// RateLimit is a middleware limiting the number of requests of each client, identified by Key.
+//
+// Each response has the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers.
+// (see draft-ietf-httpapi-ratelimit-headers) The requests exceeding the quota fail with errors.TooManyRequests (429)
+// and a Retry-After header.
+//
+// If the Store fails, the error is logged and the request is served.
+// The rate limited requests are logged as errors by Log: see Log.LevelByStatus to log them at another level.
+type RateLimit struct {
+	// Algorithm is the algorithm counting the requests. If empty, TokenBucket is used.
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Period.
+	Limit int
+	// Period is the duration of the quota.
+	Period time.Duration
+	// Burst is the number of requests allowed at once with TokenBucket. If 0, Limit is used.
+	Burst int
+	// Key returns the key of the client sending the request. If nil, KeyByIP is used.
+	Key RateLimitKey
+	// Store stores the quotas consumed by the keys.
+	// If nil, each route has its own MemoryRateLimitStore, created once by the first request of the route,
+	// even for the handlers registered with a factory, wrapped for each request.
+	// Set a Store shared by several middlewares to apply the same quota to all their routes.
+	Store RateLimitStore
+}
+
+// MakeRateLimit returns a RateLimit allowing limit requests per period, using a TokenBucket by client IP.
+func MakeRateLimit(limit int, period time.Duration) RateLimit {
+	return RateLimit{
+		Algorithm: TokenBucket,
+		Limit:     limit,
+		Period:    period,
+		Key:       KeyByIP,
+	}
+}
+
+// SetAlgorithm set Algorithm and return current instance
+func (m RateLimit) SetAlgorithm(algorithm RateLimitAlgorithm) RateLimit {
+	m.Algorithm = algorithm
+	return m
+}
+
+// SetBurst set Burst and return current instance
+func (m RateLimit) SetBurst(burst int) RateLimit {
+	m.Burst = burst
+	return m
+}
+
+// SetKey set Key and return current instance
+func (m RateLimit) SetKey(key RateLimitKey) RateLimit {
+	m.Key = key
+	return m
+}
+
+// SetStore set Store and return current instance
+func (m RateLimit) SetStore(store RateLimitStore) RateLimit {
+	m.Store = store
+	return m
+}
+
+// Wrap implements the request.Middleware interface
+func (m RateLimit) Wrap(h handler.Handler) handler.Handler {
+	key := m.Key
+	if key == nil {
+		key = KeyByIP
+	}
+
+	quota := m.quota()
+
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if quota.Limit <= 0 || quota.Period <= 0 {
+			return h.Serve(w, r)
+		}
+
+		k := key(r)
+		if k == "" {
+			return h.Serve(w, r)
+		}
+
+		store := m.Store
+		if store == nil {
+			store = routeRateLimitStore(r, quota)
+		}
+
+		result, err := store.Take(r.Context(), k, quota)
+		if err != nil {
+			gLog.Warn(r.Context()).
+				With(zap.String("key", k), zap.Error(err)).
+				LogMsg("rate limit store failed: request served without limit")
+
+			return h.Serve(w, r)
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(quota.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", quota.Limit, ceilSeconds(quota.Period)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+
+			return nil, errors.TooManyRequests("rate_limited", "too many requests, retry in %d seconds", retryAfter)
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// Doc implements the openapi.Documented interface
+func (m RateLimit) Doc(builder *openapi.DocBuilder) error {
+	builder.WithError(
+		http.StatusTooManyRequests,
+		"rate_limited",
+		fmt.Sprintf("More than %d requests per %s", m.Limit, m.Period),
+	)
+
+	docResponseHeaders(builder, http.StatusTooManyRequests, map[string]string{
+		"Retry-After":         "Number of seconds before the next request is allowed.",
+		"RateLimit-Limit":     "Number of requests allowed per window.",
+		"RateLimit-Remaining": "Number of requests still allowed in the current window.",
+		"RateLimit-Reset":     "Number of seconds until the quota is fully restored.",
+		"RateLimit-Policy":    "Quota policy: the number of requests allowed per window of w seconds.",
+	})
+
+	return builder.Error()
+}
+
+func (m RateLimit) quota() RateLimitQuota {
+	algorithm := m.Algorithm
+	if algorithm == "" {
+		algorithm = TokenBucket
+	}
+
+	return RateLimitQuota{
+		Algorithm: algorithm,
+		Limit:     m.Limit,
+		Period:    m.Period,
+		Burst:     m.Burst,
+	}
+}
+
+// routeRateLimitStores are the stores of the RateLimit middlewares without Store, by route and quota.
+var routeRateLimitStores sync.Map
+
+type routeRateLimitStoreKey struct {
+	route *mux.Route
+	quota RateLimitQuota
+}
+
+// routeRateLimitStore returns the store of the route of the request, creating it if needed.
+func routeRateLimitStore(r *http.Request, quota RateLimitQuota) RateLimitStore {
+	key := routeRateLimitStoreKey{route: mux.CurrentRoute(r), quota: quota}
+	if store, ok := routeRateLimitStores.Load(key); ok {
+		return store.(RateLimitStore)
+	}
+
+	store, _ := routeRateLimitStores.LoadOrStore(key, NewMemoryRateLimitStore())
+
+	return store.(RateLimitStore)
+}
+
+// docResponseHeaders documents the headers of the response with the given status code, by name.
+func docResponseHeaders(builder *openapi.DocBuilder, statusCode int, headers map[string]string) {
+	statusCodeStr := strconv.Itoa(statusCode)
+	resp := builder.Operation().Responses.MapOfResponseOrRefValues[statusCodeStr]
+
+	for name, description := range headers {
+		description := description
+		resp.ResponseEns().WithHeadersItem(name, openapi3.HeaderOrRef{
+			Header: &openapi3.Header{
+				Description: &description,
+				Schema: &openapi3.SchemaOrRef{
+					Schema: new(openapi3.Schema).WithType(openapi3.SchemaTypeString),
+				},
+			},
+		})
+	}
+
+	builder.Operation().Responses.WithMapOfResponseOrRefValuesItem(statusCodeStr, resp)
+}
+
+// ceilSeconds returns the given duration in seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm is the algorithm counting the requests of a RateLimit.
+type RateLimitAlgorithm string
+
+// Supported rate limit algorithms.
+const (
+	// TokenBucket allows bursts of Burst requests, refilled at the rate of Limit requests per Period.
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+	// SlidingWindow allows Limit requests in any window of Period, estimated from the counts of the current
+	// and the previous fixed windows.
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitQuota is the quota of requests allowed for a key.
+type RateLimitQuota struct {
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Period.
+	Limit int
+	// Period is the duration of the quota.
+	Period time.Duration
+	// Burst is the capacity of the token bucket. (TokenBucket only)
+	Burst int
+}
+
+// RateLimitResult is the state of the quota of a key, after a request.
+type RateLimitResult struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+	// Remaining is the number of requests still allowed.
+	Remaining int
+	// Reset is the duration until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the duration until the next request is allowed, if the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore stores the quotas consumed by the keys of a RateLimit.
+//
+// The default store is a MemoryRateLimitStore, limiting the requests served by the current instance only.
+// Implement it with a shared backend (ie: Redis) to limit the requests served by all the instances.
+type RateLimitStore interface {
+	// Take consumes a request from the quota of the given key, if it is allowed.
+	Take(ctx context.Context, key string, quota RateLimitQuota) (RateLimitResult, error)
+}
+
+// memoryRateLimitSweepInterval is the interval between two removals of the expired keys of a MemoryRateLimitStore.
+const memoryRateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore is a RateLimitStore keeping the quotas in memory.
+// The keys are removed once their quota is fully restored.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// rateLimitEntry is the state of a key: the tokens of a TokenBucket, or the counts of a SlidingWindow.
+type rateLimitEntry struct {
+	tokens      float64
+	updated     time.Time
+	windowStart time.Time
+	previous    int
+	current     int
+	expires     time.Time
+}
+
+// NewMemoryRateLimitStore returns a new empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries: make(map[string]*rateLimitEntry),
+		now:     time.Now,
+	}
+}
+
+// Take implements the RateLimitStore interface
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, quota RateLimitQuota) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > memoryRateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = &rateLimitEntry{
+			tokens:      float64(quota.burst()),
+			updated:     now,
+			windowStart: now,
+		}
+		s.entries[key] = entry
+	}
+
+	if quota.Algorithm == SlidingWindow {
+		return entry.takeSlidingWindow(now, quota), nil
+	}
+
+	return entry.takeTokenBucket(now, quota), nil
+}
+
+// Len returns the number of keys in the store.
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+func (e *rateLimitEntry) takeTokenBucket(now time.Time, quota RateLimitQuota) RateLimitResult {
+	burst := float64(quota.burst())
+	rate := float64(quota.Limit) / float64(quota.Period) // tokens per nanosecond
+
+	e.tokens = math.Min(burst, e.tokens+float64(now.Sub(e.updated))*rate)
+	e.updated = now
+
+	var result RateLimitResult
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+
+	result.Remaining = int(e.tokens)
+	result.Reset = time.Duration(math.Ceil((burst - e.tokens) / rate))
+	e.expires = now.Add(result.Reset)
+
+	return result
+}
+
+func (e *rateLimitEntry) takeSlidingWindow(now time.Time, quota RateLimitQuota) RateLimitResult {
+	if elapsed := now.Sub(e.windowStart); elapsed >= quota.Period {
+		if elapsed < 2*quota.Period {
+			e.previous = e.current
+		} else {
+			e.previous = 0
+		}
+
+		e.current = 0
+		e.windowStart = e.windowStart.Add(elapsed.Truncate(quota.Period))
+	}
+
+	elapsed := now.Sub(e.windowStart)
+	weight := 1 - float64(elapsed)/float64(quota.Period)
+	count := float64(e.previous)*weight + float64(e.current)
+
+	var result RateLimitResult
+	if count+1 <= float64(quota.Limit) {
+		e.current++
+		count++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = e.slidingWindowRetryAfter(elapsed, quota)
+	}
+
+	result.Remaining = int(math.Max(0, math.Floor(float64(quota.Limit)-count)))
+	result.Reset = 2*quota.Period - elapsed
+	e.expires = e.windowStart.Add(2 * quota.Period)
+
+	return result
+}
+
+// slidingWindowRetryAfter returns the duration until the weight of the previous window is low enough
+// to allow a request. If the current window is full, it becomes the previous window of the next one.
+func (e *rateLimitEntry) slidingWindowRetryAfter(elapsed time.Duration, quota RateLimitQuota) time.Duration {
+	available := float64(quota.Limit - 1 - e.current)
+	if available < 0 {
+		return quota.Period - elapsed + decayDuration(e.current, float64(quota.Limit-1), quota.Period)
+	}
+
+	retryAfter := decayDuration(e.previous, available, quota.Period) - elapsed
+	if retryAfter <= 0 {
+		return time.Millisecond
+	}
+
+	return retryAfter
+}
+
+// decayDuration returns the elapsed duration of a window from which the weighted count of the previous window
+// is lower than available: previous * (1 - elapsed / period) <= available.
+func decayDuration(previous int, available float64, period time.Duration) time.Duration {
+	if float64(previous) <= available {
+		return 0
+	}
+
+	return time.Duration((1 - available/float64(previous)) * float64(period))
+}
+
+func (q RateLimitQuota) burst() int {
+	if q.Burst <= 0 {
+		return q.Limit
+	}
+
+	return q.Burst
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/openapi"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	for _, algorithm := range []RateLimitAlgorithm{TokenBucket, SlidingWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			h := MakeResponseWriter().Wrap(MakeRateLimit(2, time.Minute).
+				SetAlgorithm(algorithm).
+				SetKey(KeyByHeader("X-API-Key")).
+				SetStore(store).
+				Wrap(noopHandler))
+
+			serve := func(apiKey string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("X-API-Key", apiKey)
+
+				w := httptest.NewRecorder()
+				_, _ = h.Serve(w, r)
+
+				return w
+			}
+
+			w := serve(string(algorithm))
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+			assert.Equal(t, http.StatusNoContent, serve(string(algorithm)).Code)
+
+			w = serve(string(algorithm))
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+			retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After"))
+			assert.Equal(t, map[RateLimitAlgorithm]int{TokenBucket: 30, SlidingWindow: 90}[algorithm], retryAfter)
+			assert.Contains(t, w.Body.String(), `"kind":"rate_limited"`)
+
+			// Another key has its own quota.
+			assert.Equal(t, http.StatusNoContent, serve("other-"+string(algorithm)).Code)
+
+			now = now.Add(time.Duration(retryAfter) * time.Second)
+			assert.Equal(t, http.StatusNoContent, serve(string(algorithm)).Code)
+		})
+	}
+
+	now = now.Add(time.Hour)
+	_, _ = store.Take(context.Background(), "new", RateLimitQuota{Limit: 1, Period: time.Second})
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestRateLimit_routeStore(t *testing.T) {
+	// The route stores are global: the key is unique to run the test several times.
+	key := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	m := RateLimit{Limit: 1, Period: time.Minute, Key: func(*http.Request) string { return key }}
+
+	serve := func() *httptest.ResponseRecorder {
+		// Each request is served by a new handler, like the routes registered with a factory.
+		h := MakeResponseWriter().Wrap(m.Wrap(noopHandler))
+
+		w := httptest.NewRecorder()
+		_, _ = h.Serve(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve().Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve().Code)
+}
+
+func TestRateLimit_Doc(t *testing.T) {
+	builder := openapi.NewDocBuilder(new(openapi3.Reflector), http.MethodGet, "/")
+	assert.NoError(t, MakeRateLimit(10, time.Second).Doc(builder))
+
+	resp := builder.Operation().Responses.MapOfResponseOrRefValues["429"].Response
+	assert.Contains(t, resp.Headers, "Retry-After")
+	assert.Contains(t, resp.Headers, "RateLimit-Remaining")
+}
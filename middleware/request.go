@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// Request is a middleware that will bind all the request parameters into a single struct:
+//   - fields with a `path` tag are set from the path parameters
+//   - fields with a `query` tag are set from the query parameters
+//   - fields with a `header` tag are set from the request headers
+//   - fields with a `cookie` tag are set from the request cookies
+//   - fields with a `json` or `formData` tag are set from the request body
+//
+// The struct is then validated the same way as the BodyDecoder does.
+type Request struct {
+	// Parameters is a pointer to the struct you want to bind your request into.
+	Parameters interface{}
+	// Decoders is the list the available Decoders by content-type.
+	// If nil, DecoderByContentType is used, with FormDecoderByContentType if Parameters has `formData` fields.
+	Decoders map[string]Decoder
+	// DefaultContentType is the default body content-type if the request don't have any.
+	// If empty, application/json is used.
+	DefaultContentType string
+	// MaxBodySize is the maximum size of the request body, once decompressed.
+	// If 0, DefaultMaxBodySize is used. If negative, there is no limit.
+	MaxBodySize int64
+}
+
+// Wrap implements the request.Middleware interface
+func (m Request) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if m.Parameters == nil {
+			return h.Serve(w, r)
+		}
+
+		defer RemoveMultipartForm(r)
+
+		if err := m.Bind(r); err != nil {
+			return nil, err
+		}
+
+		return h.Serve(w, r)
+	})
+}
+
+// Bind decodes the given request into the Parameters field and validates it.
+func (m Request) Bind(r *http.Request) error {
+	if (hasTaggedFields(m.Parameters, "json") || hasTaggedFields(m.Parameters, "formData")) && hasBody(r) {
+		if err := m.bodyDecoder().decode(r); err != nil {
+			return err
+		}
+
+		resetNonBodyFields(m.Parameters)
+	}
+
+	if err := m.bindParameters(r); err != nil {
+		return err
+	}
+
+	if err := validateRecursive(m.Parameters); err != nil {
+		return err
+	}
+
+	if v, ok := m.Parameters.(BodyValidation); ok {
+		if errValidate := v.Validate(); errValidate != nil {
+			if castedErr, casted := errValidate.(errors.Error); casted {
+				return castedErr
+			}
+
+			return errors.BadRequest("invalid_body", errValidate.Error())
+		}
+	}
+
+	return nil
+}
+
+func (m Request) bindParameters(r *http.Request) errors.Error {
+	vars := mux.Vars(r)
+	if err := bindStrings(m.Parameters, "path", func(name string) []string {
+		if val, ok := vars[name]; ok {
+			return []string{val}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	if err := bindStrings(m.Parameters, "query", func(name string) []string {
+		return query[name]
+	}); err != nil {
+		return err
+	}
+
+	if err := bindStrings(m.Parameters, "header", r.Header.Values); err != nil {
+		return err
+	}
+
+	return bindStrings(m.Parameters, "cookie", cookieValues(r))
+}
+
+// bodyDecoder returns the BodyDecoder used to decode the json fields.
+func (m Request) bodyDecoder() BodyDecoder {
+	decoder := Body(m.Parameters)
+
+	if m.Decoders != nil {
+		decoder.Decoders = m.Decoders
+	}
+
+	if m.DefaultContentType != "" {
+		decoder.DefaultContentType = m.DefaultContentType
+	}
+
+	if m.MaxBodySize != 0 {
+		decoder.MaxBodySize = m.MaxBodySize
+	}
+
+	return decoder
+}
+
+// BindingTarget implements the handler.BindingMiddleware interface
+func (m Request) BindingTarget() interface{} {
+	return m.Parameters
+}
+
+// WithBindingTarget implements the handler.BindingMiddleware interface
+func (m Request) WithBindingTarget(target interface{}) handler.Middleware {
+	m.Parameters = target
+	return m
+}
+
+// Doc implements the openapi.Documented interface
+func (m Request) Doc(builder *openapi.DocBuilder) error {
+	if m.Parameters == nil {
+		return nil
+	}
+
+	if hasTaggedFields(m.Parameters, "path") || hasTaggedFields(m.Parameters, "query") || hasTaggedFields(m.Parameters, "header") || hasTaggedFields(m.Parameters, "cookie") {
+		builder.
+			WithError(400, "invalid_param_type", "A parameter has an invalid type").
+			WithError(400, "missing_param", "A required parameter is missing")
+	}
+
+	if !hasTaggedFields(m.Parameters, "json") && !hasTaggedFields(m.Parameters, "formData") {
+		builder.WithParams(m.Parameters)
+
+		return builder.Error()
+	}
+
+	if hasTaggedFields(m.Parameters, "formData") {
+		for _, decoder := range m.bodyDecoder().Decoders {
+			if documentedDecoder, ok := decoder.(openapi.Documented); ok {
+				if err := documentedDecoder.Doc(builder); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	builder.
+		WithBody(m.Parameters).
+		WithError(400, "invalid_content_type", "Unable to resolve content type").
+		WithError(400, "body_error", "Failed to read request body").
+		WithError(400, "invalid_body_format", "Failed to decode request body").
+		WithError(400, "missing_param", "A required field is missing").
+		WithError(400, "body_validation_failed", "A field does not match the required pattern").
+		WithError(400, "enum_validation_failed", "A field value is not in the allowed enum values").
+		WithError(400, "invalid_body", "Body validation failed")
+
+	m.bodyDecoder().docLimits(builder)
+
+	return builder.Error()
+}
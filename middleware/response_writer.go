@@ -1,14 +1,17 @@
 package middleware
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/elnormous/contenttype"
 
 	"github.com/mwm-io/gapi/errors"
 	"github.com/mwm-io/gapi/handler"
 	"github.com/mwm-io/gapi/response"
+	"github.com/mwm-io/gapi/websocket"
 )
 
 // WithStatusCode is able to return its http status code.
@@ -16,6 +19,30 @@ type WithStatusCode interface {
 	StatusCode() int
 }
 
+// beforeWriteHook is called by ResponseWriter with the encoded body of the response, before writing it.
+// It returns false if the response has been written by the hook. (see ETag)
+type beforeWriteHook func(w http.ResponseWriter, r *http.Request, statusCode int, resp interface{}, body []byte) bool
+
+// responseHooks are the hooks registered for a request by the middlewares executed after ResponseWriter.
+type responseHooks struct {
+	beforeWrite []beforeWriteHook
+}
+
+type responseHooksKey struct{}
+
+// addBeforeWriteHook registers a hook called by the ResponseWriter serving the request.
+// It returns false if the request is not served by a ResponseWriter.
+func addBeforeWriteHook(r *http.Request, hook beforeWriteHook) bool {
+	hooks, ok := r.Context().Value(responseHooksKey{}).(*responseHooks)
+	if !ok {
+		return false
+	}
+
+	hooks.beforeWrite = append(hooks.beforeWrite, hook)
+
+	return true
+}
+
 // ResponseWriter is a middleware that will take the response from the next handler
 // and write it into the response.
 // It will choose the content type based on the request Accept header.
@@ -78,8 +105,16 @@ func (m ResponseWriter) AddEncoder(contentType string, encoder Encoder) Response
 // Wrap implements the request.Middleware interface
 func (m ResponseWriter) Wrap(h handler.Handler) handler.Handler {
 	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		r = r.WithContext(context.WithValue(r.Context(), responseHooksKey{}, &responseHooks{}))
+		r = m.withWebSocketCodec(r)
+
 		resp, err := h.Serve(w, r)
 
+		if _, ok := resp.(response.Hijacked); ok {
+			// The connection has been taken over by the handler: nothing can be written anymore.
+			return nil, nil
+		}
+
 		m.StatusCode = m.StatusCodeFromHTTPServeResult(resp, err)
 
 		var errW error
@@ -97,6 +132,26 @@ func (m ResponseWriter) Wrap(h handler.Handler) handler.Handler {
 	})
 }
 
+// withWebSocketCodec returns the request carrying the Encoder of the response writer, and the matching Decoder
+// of DecoderByContentType, for the messages of the websocket connection it may be upgraded to. (see websocket.WithCodec)
+func (m ResponseWriter) withWebSocketCodec(r *http.Request) *http.Request {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r
+	}
+
+	contentType, encoder, err := m.resolveStreamEncoder(r)
+	if err != nil {
+		return r
+	}
+
+	var decoder websocket.Decoder
+	if d, ok := DecoderByContentType[contentType]; ok {
+		decoder = d
+	}
+
+	return r.WithContext(websocket.WithCodec(r.Context(), encoder, decoder))
+}
+
 // StatusCodeFromHTTPServeResult returns response http status code from http.Serve result
 func (m ResponseWriter) StatusCodeFromHTTPServeResult(resp interface{}, err error) int {
 	if err != nil {
@@ -129,10 +184,20 @@ func (m ResponseWriter) writeResponse(w http.ResponseWriter, r *http.Request, re
 		return nil
 	}
 
+	if versioned, ok := resp.(Versioned); ok && m.StatusCode < http.StatusMultipleChoices {
+		if etag := QuoteETag(versioned.Version()); etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+	}
+
 	switch v := resp.(type) {
 	case response.Redirect:
 		http.Redirect(w, r, v.URL, v.StatusCode)
 		return nil
+	case response.EventStream:
+		return m.writeEventStream(w, r, v)
+	case *response.EventStream:
+		return m.writeEventStream(w, r, *v)
 	case io.ReadCloser:
 		defer v.Close()
 		w.WriteHeader(m.StatusCode)
@@ -145,6 +210,10 @@ func (m ResponseWriter) writeResponse(w http.ResponseWriter, r *http.Request, re
 		return err
 
 	case []byte:
+		if !m.beforeWrite(w, r, resp, v) {
+			return nil
+		}
+
 		w.WriteHeader(m.StatusCode)
 		_, err := w.Write(v)
 		return err
@@ -162,6 +231,10 @@ func (m ResponseWriter) writeResponse(w http.ResponseWriter, r *http.Request, re
 			return errMarshal
 		}
 
+		if !m.beforeWrite(w, r, resp, body) {
+			return nil
+		}
+
 		w.WriteHeader(m.StatusCode)
 
 		_, errW := w.Write(body)
@@ -170,6 +243,22 @@ func (m ResponseWriter) writeResponse(w http.ResponseWriter, r *http.Request, re
 	}
 }
 
+// beforeWrite calls the hooks registered for the request, and returns false if the response must not be written.
+func (m ResponseWriter) beforeWrite(w http.ResponseWriter, r *http.Request, resp interface{}, body []byte) bool {
+	hooks, ok := r.Context().Value(responseHooksKey{}).(*responseHooks)
+	if !ok {
+		return true
+	}
+
+	for _, hook := range hooks.beforeWrite {
+		if !hook(w, r, m.StatusCode, resp, body) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (m ResponseWriter) resolveContentType(r *http.Request) (string, Encoder, error) {
 	if m.ForcedContentType != "" {
 		encoder, ok := m.Encoders[m.ForcedContentType]
@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	gLog "github.com/mwm-io/gapi/log"
+	"github.com/mwm-io/gapi/openapi"
+	"github.com/mwm-io/gapi/response"
+)
+
+// DefaultTimeout is the default maximum duration of a request served with Timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Timeout is a middleware limiting the duration of the next handlers.
+//
+// The request context has a deadline, so that the handler and the calls it makes are cancelled when it is exceeded.
+// The handler is then abandoned: the request fails with a "timeout" error, errors.ServiceUnavailable (503) by default,
+// and everything the handler writes after the deadline is discarded with http.ErrHandlerTimeout.
+// If the handler already started to write its response, the response is truncated.
+//
+// When BudgetHeader is set, the client can shorten the deadline with the remaining time it has to wait for the
+// response, in milliseconds or as a duration. (ie: `X-Request-Budget: 1500` or `X-Request-Budget: 1.5s`)
+//
+// It must be executed after ResponseWriter. (ie: in the handler middlewares)
+type Timeout struct {
+	// Duration is the maximum duration of the request. If 0, DefaultTimeout is used.
+	Duration time.Duration
+	// StatusCode is the status code of the timeout error: http.StatusServiceUnavailable or http.StatusGatewayTimeout.
+	// If 0, http.StatusServiceUnavailable is used.
+	StatusCode int
+	// BudgetHeader is the name of the request header with the time budget of the client. If empty, it is ignored.
+	BudgetHeader string
+}
+
+// MakeTimeout returns a Timeout with the given duration.
+func MakeTimeout(duration time.Duration) Timeout {
+	return Timeout{
+		Duration:   duration,
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
+// SetStatusCode set StatusCode and return current instance
+func (m Timeout) SetStatusCode(statusCode int) Timeout {
+	m.StatusCode = statusCode
+	return m
+}
+
+// SetBudgetHeader set BudgetHeader and return current instance
+func (m Timeout) SetBudgetHeader(header string) Timeout {
+	m.BudgetHeader = header
+	return m
+}
+
+// Wrap implements the request.Middleware interface
+func (m Timeout) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		duration := m.duration(r)
+		if duration <= 0 {
+			return nil, m.timeoutError(duration)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), duration)
+		defer cancel()
+
+		tw := &timeoutWriter{
+			w:      w,
+			header: w.Header().Clone(),
+		}
+
+		type result struct {
+			resp     interface{}
+			err      error
+			panicked interface{}
+		}
+
+		done := make(chan result, 1)
+		start := time.Now()
+
+		go func() {
+			var res result
+			defer func() {
+				// The panic is forwarded to the middlewares executed before, like Recover.
+				res.panicked = recover()
+				done <- res
+			}()
+
+			res.resp, res.err = h.Serve(tw, r.WithContext(ctx))
+		}()
+
+		select {
+		case res := <-done:
+			if res.panicked != nil {
+				panic(res.panicked)
+			}
+
+			tw.finish()
+
+			return res.resp, res.err
+
+		case <-ctx.Done():
+			written := tw.timeout()
+
+			gLog.Warn(r.Context()).
+				With(zap.String("method", r.Method), zap.String("path", r.URL.Path)).
+				LogMsg("handler abandoned after %s", duration)
+
+			go func() {
+				res := <-done
+				if res.panicked != nil {
+					// Nothing recovers the panic anymore: it is only logged.
+					gLog.Error(r.Context()).
+						With(zap.String("method", r.Method), zap.String("path", r.URL.Path)).
+						LogError(errors.InternalServerError("panic", "abandoned handler panicked after %s: %v", time.Since(start), res.panicked))
+					return
+				}
+
+				gLog.Warn(r.Context()).
+					With(zap.String("method", r.Method), zap.String("path", r.URL.Path)).
+					LogMsg("abandoned handler returned after %s", time.Since(start))
+			}()
+
+			err := m.timeoutError(duration).WithError(ctx.Err())
+			if written {
+				// The response has already been started: it can't be replaced by the error.
+				return response.Hijacked{}, err
+			}
+
+			return nil, err
+		}
+	})
+}
+
+// Doc implements the openapi.Documented interface
+func (m Timeout) Doc(builder *openapi.DocBuilder) error {
+	if m.BudgetHeader != "" {
+		tag := fmt.Sprintf(`header:"%s" description:"Time budget of the client, in milliseconds or as a duration. (ie: 1500 or 1.5s)"`, m.BudgetHeader)
+		params := reflect.StructOf([]reflect.StructField{
+			{Name: "Budget", Type: reflect.TypeOf(""), Tag: reflect.StructTag(tag)},
+		})
+
+		builder.WithParams(reflect.New(params).Elem().Interface())
+	}
+
+	builder.WithError(m.statusCode(), "timeout", fmt.Sprintf("The request took more than %s", m.maxDuration()))
+
+	return builder.Error()
+}
+
+// duration returns the duration of the given request: the configured duration, shortened by the client budget.
+func (m Timeout) duration(r *http.Request) time.Duration {
+	duration := m.maxDuration()
+	if m.BudgetHeader == "" {
+		return duration
+	}
+
+	budget, ok := parseBudget(r.Header.Get(m.BudgetHeader))
+	if ok && budget < duration {
+		return budget
+	}
+
+	return duration
+}
+
+func (m Timeout) maxDuration() time.Duration {
+	if m.Duration <= 0 {
+		return DefaultTimeout
+	}
+
+	return m.Duration
+}
+
+func (m Timeout) statusCode() int {
+	if m.StatusCode == 0 {
+		return http.StatusServiceUnavailable
+	}
+
+	return m.StatusCode
+}
+
+func (m Timeout) timeoutError(duration time.Duration) errors.Error {
+	if duration <= 0 {
+		return errors.ServiceUnavailable("timeout", "the request budget is exhausted").
+			WithStatus(m.statusCode())
+	}
+
+	return errors.ServiceUnavailable("timeout", "the request took more than %s", duration).
+		WithStatus(m.statusCode())
+}
+
+// parseBudget parses a time budget, in milliseconds or as a duration.
+func parseBudget(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return duration, true
+}
+
+// timeoutWriter is a http.ResponseWriter discarding everything written after the timeout of the request.
+//
+// The handler has its own copy of the headers, so that they aren't modified while the timeout error is written.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+// Header implements the http.ResponseWriter interface
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader implements the http.ResponseWriter interface
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	tw.copyHeader()
+	tw.wroteHeader = true
+	tw.w.WriteHeader(statusCode)
+}
+
+// Write implements the http.ResponseWriter interface
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.copyHeader()
+	tw.wroteHeader = true
+
+	return tw.w.Write(p)
+}
+
+// Flush implements the http.Flusher interface
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		tw.copyHeader()
+		tw.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+
+	hijacker, ok := tw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	tw.wroteHeader = true
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter. (see http.ResponseController)
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// finish copies the headers set by the handler, once it returned before the timeout.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.copyHeader()
+}
+
+// timeout discards the next writes. It returns true if the handler already started to write the response.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.timedOut = true
+
+	return tw.wroteHeader
+}
+
+// copyHeader replaces the headers of the response with the ones of the handler, until the response is written.
+func (tw *timeoutWriter) copyHeader() {
+	if tw.wroteHeader {
+		return
+	}
+
+	dst := tw.w.Header()
+	for key := range dst {
+		delete(dst, key)
+	}
+
+	for key, values := range tw.header {
+		dst[key] = append([]string(nil), values...)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestTimeout(t *testing.T) {
+	returned := make(chan error, 1)
+	h := MakeResponseWriter().Wrap(MakeTimeout(50 * time.Millisecond).SetBudgetHeader("X-Request-Budget").Wrap(
+		handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+			delay, _ := time.ParseDuration(r.URL.Query().Get("delay"))
+
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				// Simulate a handler ignoring the cancellation for a while.
+				time.Sleep(10 * time.Millisecond)
+				w.Header().Set("X-Late", "true")
+				_, err := w.Write([]byte("late"))
+				returned <- err
+
+				return nil, r.Context().Err()
+			}
+
+			w.Header().Set("X-Handler", "true")
+
+			return map[string]string{"status": "ok"}, nil
+		})))
+
+	serve := func(delay, budget string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/?delay="+delay, nil)
+		if budget != "" {
+			r.Header.Set("X-Request-Budget", budget)
+		}
+
+		w := httptest.NewRecorder()
+		_, _ = h.Serve(w, r)
+
+		return w
+	}
+
+	w := serve("1ms", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Handler"))
+
+	w = serve("1s", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"kind":"timeout"`)
+	assert.Equal(t, http.ErrHandlerTimeout, <-returned)
+	assert.Empty(t, w.Header().Get("X-Late"))
+
+	start := time.Now()
+	w = serve("1s", "5ms")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	<-returned
+
+	w = serve("1ms", "0")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -82,11 +82,23 @@ func (b *DocBuilder) WithTags(tags ...string) *DocBuilder {
 	return b
 }
 
+// WithSecurity adds a security requirement to the operation: the name of a security scheme and its required scopes.
+func (b *DocBuilder) WithSecurity(name string, scopes ...string) *DocBuilder {
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	b.operation.Security = append(b.operation.Security, map[string][]string{name: scopes})
+	return b
+}
+
 // WithBody configure a request body to the operation
 // Allowed options :
 // - WithDescription to add a description to body
 // - WithExample to add example(s) as body
-// TODO: Find a way to support non json body like CSV, files, multi part, url encoded ...
+// Fields with a 'formData' tag are documented as an application/x-www-form-urlencoded body,
+// or as a multipart/form-data body if it contains a *multipart.FileHeader field.
+// TODO: Find a way to support non json body like CSV
 func (b *DocBuilder) WithBody(body interface{}, options ...BuilderOption) *DocBuilder {
 	var c builderOptions
 	c.applyOptions(options...)
@@ -132,9 +144,11 @@ func (b *DocBuilder) WithBodyExample(value interface{}) *DocBuilder {
 	return b
 }
 
-// WithParams configure path and query parameters to the operation
+// WithParams configure path, query, header and cookie parameters to the operation
 // To set path parameters use a struct with 'path' tag
 // To set query parameters use a struct with 'query' tag
+// To set header parameters use a struct with 'header' tag
+// To set cookie parameters use a struct with 'cookie' tag
 func (b *DocBuilder) WithParams(body interface{}) *DocBuilder {
 	if err := b.reflector.SetRequest(b.operation, body, b.httpMethod); err != nil {
 		b.err = append(b.err, err)
@@ -208,6 +222,22 @@ func (b *DocBuilder) WithResponse(output interface{}, options ...BuilderOption)
 	return b
 }
 
+// WithEventStream configure a text/event-stream response (see response.EventStream) for current operation.
+// The given data is the data of the streamed events, and the Last-Event-ID header is documented as a parameter.
+// Allowed options are the same as WithResponse.
+func (b *DocBuilder) WithEventStream(data interface{}, options ...BuilderOption) *DocBuilder {
+	options = append([]BuilderOption{
+		WithMimeType("text/event-stream"),
+		WithDescription("Stream of Server-Sent Events, the data of each event is described by the schema."),
+	}, options...)
+
+	return b.
+		WithParams(struct {
+			LastEventID string `header:"Last-Event-ID" description:"ID of the last event received by the client, to resume the stream after a reconnection."`
+		}{}).
+		WithResponse(data, options...)
+}
+
 // WithError configure an error for current operation
 // Allowed options :
 // - WithDescription to add a description to error response
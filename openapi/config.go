@@ -19,10 +19,12 @@ const (
 // - SpecOpenAPIURI: the URL for the json openapi definition of your API.
 // - IgnoredPaths: the paths that shouldn't be included in the documentation.
 // - Auth: your auth system to protect your documentation.
-var Config config
+//
+// You can also create your own DocConfig if you need several documentations with different configurations. (see server.App)
+var Config DocConfig
 
-// config contains all the options for your API documentation.
-type config struct {
+// DocConfig contains all the options for your API documentation.
+type DocConfig struct {
 	// DocURI is the URI for the display of your documentation.
 	DocURI string
 	// SpecOpenAPIURI is the URL for the json openapi definition of your API.
@@ -35,8 +37,8 @@ type config struct {
 	FaviconURL string
 }
 
-// GetDocURI return config.DocURI if a values was set otherwise DefaultDocURI is returned
-func (c config) GetDocURI() string {
+// GetDocURI return c.DocURI if a values was set otherwise DefaultDocURI is returned
+func (c DocConfig) GetDocURI() string {
 	if c.DocURI != "" {
 		return c.DocURI
 	}
@@ -44,8 +46,8 @@ func (c config) GetDocURI() string {
 	return DefaultDocURI
 }
 
-// GetSpecOpenAPIURI return config.SpecOpenAPIURI if a values was set otherwise DefaultDocURI is returned
-func (c config) GetSpecOpenAPIURI() string {
+// GetSpecOpenAPIURI return DocConfig.SpecOpenAPIURI if a values was set otherwise DefaultDocURI is returned
+func (c DocConfig) GetSpecOpenAPIURI() string {
 	if c.SpecOpenAPIURI != "" {
 		return c.SpecOpenAPIURI
 	}
@@ -54,11 +56,13 @@ func (c config) GetSpecOpenAPIURI() string {
 }
 
 // GetAuthReceiverURI return
-func (c config) GetAuthReceiverURI() string {
+func (c DocConfig) GetAuthReceiverURI() string {
 	return fmt.Sprintf("%s%s", c.GetDocURI(), "oauth-receiver.html")
 }
 
-func (c config) ignoredPaths() []string {
+// GetIgnoredPaths returns the paths that shouldn't be included in the documentation,
+// including the documentation paths.
+func (c DocConfig) GetIgnoredPaths() []string {
 	ignoredPaths := []string{
 		c.GetDocURI(),
 		c.GetSpecOpenAPIURI(),
@@ -68,8 +72,8 @@ func (c config) ignoredPaths() []string {
 	return append(ignoredPaths, c.IgnoredPaths...)
 }
 
-// GetDocPageTitle return config.DocPageTitle if a values was set otherwise DocPageTitle is returned
-func (c config) GetDocPageTitle() string {
+// GetDocPageTitle return DocConfig.DocPageTitle if a values was set otherwise DocPageTitle is returned
+func (c DocConfig) GetDocPageTitle() string {
 	if c.DocPageTitle != "" {
 		return c.DocPageTitle
 	}
@@ -77,7 +81,7 @@ func (c config) GetDocPageTitle() string {
 	return DocPageTitle
 }
 
-// GetFaviconURL return config.FaviconURL
-func (c config) GetFaviconURL() string {
+// GetFaviconURL return DocConfig.FaviconURL
+func (c DocConfig) GetFaviconURL() string {
 	return c.FaviconURL
 }
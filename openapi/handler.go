@@ -17,13 +17,21 @@ type SpecOpenAPIHandler struct {
 	reflectorOnce   sync.Once
 	computeDocError error
 	router          *mux.Router
+	config          *DocConfig
 }
 
 // NewSpecOpenAPIHandler builds a new SpecOpenAPIHandler, serving the api definition from the openapi3.Reflector,
 // and checking auth access with the given Authorization.
 func NewSpecOpenAPIHandler(router *mux.Router, middlewares ...handler.Middleware) *SpecOpenAPIHandler {
+	return NewSpecOpenAPIHandlerWithConfig(router, &Config, middlewares...)
+}
+
+// NewSpecOpenAPIHandlerWithConfig builds a new SpecOpenAPIHandler like NewSpecOpenAPIHandler,
+// using the given DocConfig instead of the global Config.
+func NewSpecOpenAPIHandlerWithConfig(router *mux.Router, config *DocConfig, middlewares ...handler.Middleware) *SpecOpenAPIHandler {
 	return &SpecOpenAPIHandler{
 		router: router,
+		config: config,
 		WithMiddlewares: handler.WithMiddlewares{
 			MiddlewareList: middlewares,
 		},
@@ -36,8 +44,8 @@ func NewSpecOpenAPIHandler(router *mux.Router, middlewares ...handler.Middleware
 func (h *SpecOpenAPIHandler) getReflector() (*openapi3.Reflector, error) {
 	h.reflectorOnce.Do(func() {
 		h._reflector = new(openapi3.Reflector)
-		h._reflector.SpecEns().Info.WithTitle(Config.GetDocPageTitle())
-		h.computeDocError = PopulateReflector(h._reflector, h.router, Config.ignoredPaths())
+		h._reflector.SpecEns().Info.WithTitle(h.config.GetDocPageTitle())
+		h.computeDocError = PopulateReflector(h._reflector, h.router, h.config.GetIgnoredPaths())
 	})
 
 	return h._reflector, h.computeDocError
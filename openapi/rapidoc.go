@@ -12,12 +12,19 @@ import (
 type RapiDocHandler struct {
 	handler.WithMiddlewares
 	openAPIJsonURL string
+	config         *DocConfig
 }
 
 // NewRapiDocHandler build a new RapiDocHandler.
 func NewRapiDocHandler(middlewares ...handler.Middleware) handler.Handler {
+	return NewRapiDocHandlerWithConfig(&Config, middlewares...)
+}
+
+// NewRapiDocHandlerWithConfig build a new RapiDocHandler using the given DocConfig instead of the global Config.
+func NewRapiDocHandlerWithConfig(config *DocConfig, middlewares ...handler.Middleware) handler.Handler {
 	return &RapiDocHandler{
-		openAPIJsonURL: Config.GetSpecOpenAPIURI(),
+		openAPIJsonURL: config.GetSpecOpenAPIURI(),
+		config:         config,
 		WithMiddlewares: handler.WithMiddlewares{
 			MiddlewareList: middlewares,
 		},
@@ -29,7 +36,7 @@ func (h RapiDocHandler) Serve(_ http.ResponseWriter, _ *http.Request) (interface
 	// TODO add customisation options : like icon, theme etc...
 
 	var favicon string
-	if faviconURL := Config.GetFaviconURL(); faviconURL != "" {
+	if faviconURL := h.config.GetFaviconURL(); faviconURL != "" {
 		favicon = fmt.Sprintf(`<link rel="icon" type="image/x-icon" href="%s">`, faviconURL)
 	}
 
@@ -42,7 +49,7 @@ func (h RapiDocHandler) Serve(_ http.ResponseWriter, _ *http.Request) (interface
     }
 </style>
 <head>
-  <title>` + Config.GetDocPageTitle() + `</title>
+  <title>` + h.config.GetDocPageTitle() + `</title>
   ` + favicon + `
   <meta charset="utf-8"> <!-- Important: rapi-doc uses utf8 characters -->
   <script type="module" src="https://unpkg.com/rapidoc/dist/rapidoc-min.js"></script>
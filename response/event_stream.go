@@ -0,0 +1,119 @@
+package response
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultHeartbeatInterval is the default interval between two heartbeats of an EventStream.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the event id. The client sends the id of the last received event in the Last-Event-ID header when it reconnects.
+	// It can't contain line breaks.
+	ID string
+	// Name is the event type. If empty, the client receives a "message" event. It can't contain line breaks.
+	Name string
+	// Data is the event data. A string or a []byte is sent as is, any other value is encoded
+	// with the content type negotiated by the response writer. (ie: JSON)
+	Data interface{}
+	// Retry is the reconnection time the client must use if the connection is lost. Ignored if 0.
+	Retry time.Duration
+}
+
+// EventIterator returns the next event of an EventStream.
+// It returns io.EOF when there is no more event.
+// Its context is cancelled when the client disconnects.
+type EventIterator func(ctx context.Context) (Event, error)
+
+// EventStream can be used as handler response to stream Server-Sent Events (text/event-stream).
+// Each event is flushed as soon as it is written, and the stream stops when the client disconnects.
+//
+// The events are read from Events, or from Next if Events is nil.
+type EventStream struct {
+	// Events is the channel of events to send. The stream ends when it is closed.
+	Events <-chan Event
+	// Next returns the events to send when Events is nil.
+	// If it returns an error other than io.EOF, the error is sent as an "error" event and the stream ends.
+	Next EventIterator
+	// HeartbeatInterval is the interval between two heartbeats (comment lines) sent when there is no event,
+	// to keep the connection open through proxies. If 0, DefaultHeartbeatInterval is used. If negative, no heartbeat is sent.
+	HeartbeatInterval time.Duration
+}
+
+// NewEventStream returns an EventStream sending the events of the given channel.
+func NewEventStream(events <-chan Event) EventStream {
+	return EventStream{
+		Events: events,
+	}
+}
+
+// NewEventStreamFromIterator returns an EventStream sending the events returned by next.
+func NewEventStreamFromIterator(next EventIterator) EventStream {
+	return EventStream{
+		Next: next,
+	}
+}
+
+// SetHeartbeatInterval set HeartbeatInterval and return current instance
+func (s EventStream) SetHeartbeatInterval(interval time.Duration) EventStream {
+	s.HeartbeatInterval = interval
+	return s
+}
+
+// Heartbeat returns the interval between two heartbeats, or 0 if heartbeats are disabled.
+func (s EventStream) Heartbeat() time.Duration {
+	switch {
+	case s.HeartbeatInterval < 0:
+		return 0
+	case s.HeartbeatInterval == 0:
+		return DefaultHeartbeatInterval
+	default:
+		return s.HeartbeatInterval
+	}
+}
+
+// Channel returns the channel of events to send, until ctx is done.
+// If the stream uses an EventIterator, an error it returns is sent to errCh.
+func (s EventStream) Channel(ctx context.Context) (events <-chan Event, errCh <-chan error) {
+	if s.Events != nil || s.Next == nil {
+		return s.Events, nil
+	}
+
+	eventCh := make(chan Event)
+	iteratorErrCh := make(chan error, 1)
+
+	go func() {
+		defer close(eventCh)
+
+		for {
+			event, err := s.Next(ctx)
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				iteratorErrCh <- err
+				return
+			}
+
+			select {
+			case eventCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return eventCh, iteratorErrCh
+}
+
+// LastEventID returns the id of the last event received by the client before reconnecting,
+// from the Last-Event-ID header, or an empty string if it is a new connection.
+// Use it to resume the stream from this event.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
@@ -0,0 +1,6 @@
+package response
+
+// Hijacked can be used as handler response when the handler took over the connection (see http.Hijacker).
+// (ie: to upgrade it to a websocket connection)
+// Nothing is written by the ResponseWriter, even if the handler returned an error.
+type Hijacked struct{}
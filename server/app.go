@@ -0,0 +1,317 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/lifecycle"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// defaultApp is the App used by the package-level functions (AddHandler, UseMiddlewares, AddDocHandlers...).
+// It uses the package-level globals: middleware.Defaults, openapi.Config and the errors.AddErrorBuilders builders.
+var defaultApp = &App{
+	useGlobals: true,
+	docConfig:  &openapi.Config,
+}
+
+// App is an API application owning its own router, default middlewares, error builders,
+// query parameters decoder, documentation configuration and server options.
+//
+// Unlike the package-level functions, which share process-wide state, several App can live
+// in the same program with different configurations.
+//
+//	app := server.NewApp(server.WithPort("8080"))
+//	app.UseMiddlewares(authMiddleware)
+//	app.AddHandler(http.MethodGet, "/hello", h)
+//
+//	err := app.ServeAndHandleShutdown()
+type App struct {
+	router       *mux.Router
+	options      []Option
+	docConfig    *openapi.DocConfig
+	queryDecoder *schema.Decoder
+	// useGlobals indicates that the App uses the package-level globals instead of its own state.
+	useGlobals bool
+
+	mu            sync.RWMutex
+	middlewares   []handler.Middleware
+	errorBuilders errors.Builders
+	strict        bool
+
+	// stopping is set as soon as a manager serving the App starts stopping, so that its readiness endpoints fail.
+	stopping atomic.Bool
+
+	// middlewaresVersion is incremented each time the default middlewares change,
+	// so that the routes check their middleware chain without locking.
+	middlewaresVersion atomic.Uint64
+}
+
+// NewApp returns a new App with a new router, the default middlewares (see middleware.NewDefaults),
+// a new query parameters decoder (see middleware.NewQueryDecoder) and an empty documentation configuration.
+// The given Option are used every time the App starts a server.
+func NewApp(opts ...Option) *App {
+	return &App{
+		router:       NewMux(),
+		options:      opts,
+		docConfig:    &openapi.DocConfig{},
+		queryDecoder: middleware.NewQueryDecoder(),
+		middlewares:  middleware.NewDefaults(),
+	}
+}
+
+// Router returns the App router.
+func (a *App) Router() *mux.Router {
+	return a.router
+}
+
+// DocConfig returns the App documentation configuration. You can update it to change your documentation.
+func (a *App) DocConfig() *openapi.DocConfig {
+	return a.docConfig
+}
+
+// QueryDecoder returns the decoder used by the middleware.QueryParameters of the App handlers.
+// You can configure it (ie: RegisterConverter) before serving requests.
+// It is nil for the default App, whose handlers use the package-level decoder.
+func (a *App) QueryDecoder() *schema.Decoder {
+	return a.queryDecoder
+}
+
+// UseMiddlewares appends a given list of handler.Middleware to the App default middlewares.
+func (a *App) UseMiddlewares(middlewares ...handler.Middleware) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	defer a.middlewaresVersion.Add(1)
+
+	if a.useGlobals {
+		middleware.Defaults = append(middleware.Defaults, middlewares...)
+		return
+	}
+
+	a.middlewares = append(a.middlewares, middlewares...)
+}
+
+// Middlewares returns a copy of the App default middlewares.
+func (a *App) Middlewares() []handler.Middleware {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	defaults := a.middlewares
+	if a.useGlobals {
+		defaults = middleware.Defaults
+	}
+
+	middlewares := make([]handler.Middleware, len(defaults))
+	copy(middlewares, defaults)
+
+	return middlewares
+}
+
+// AddErrorBuilders appends custom errors.ErrorBuilder to the App.
+// They are used to transform any non gapi error returned by the App handlers.
+func (a *App) AddErrorBuilders(builders ...errors.ErrorBuilder) {
+	if a.useGlobals {
+		errors.AddErrorBuilders(builders...)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.errorBuilders = append(a.errorBuilders, builders...)
+}
+
+// defaultMiddlewares returns the App default middlewares, without copying them.
+func (a *App) defaultMiddlewares() []handler.Middleware {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.useGlobals {
+		return middleware.Defaults
+	}
+
+	return a.middlewares
+}
+
+// WrapError wraps the given error using the App error builders. (see errors.Wrap)
+func (a *App) WrapError(err error) errors.Error {
+	if a.useGlobals {
+		return errors.Wrap(err)
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.errorBuilders.Wrap(err)
+}
+
+// AddHandler register a new handler to the App router on a given method and path.
+func (a *App) AddHandler(method, path string, h handler.Handler) {
+	a.addRoute(a.router, method, path, &defaultHandleEngine{
+		getHandler: staticFactory(h),
+		static:     true,
+	})
+}
+
+// AddHandlerFactory register a new handler factory to the App router on a given method and path.
+// (see AddHandlerFactory)
+func (a *App) AddHandlerFactory(method, path string, f handler.Factory) {
+	a.addRoute(a.router, method, path, &defaultHandleEngine{
+		getHandler: f,
+	})
+}
+
+// Group returns a new RouteGroup registering its handlers on the App router. (see Group)
+func (a *App) Group(prefix string, middlewares ...handler.Middleware) *RouteGroup {
+	return a.group(a.router, prefix, middlewares...)
+}
+
+// AddDocHandlers adds the documentation handlers to the App router, using the App DocConfig. (see AddDocHandlers)
+func (a *App) AddDocHandlers(middlewares ...handler.Middleware) error {
+	return a.addDocHandlers(a.router, a.router, a.docConfig, middlewares...)
+}
+
+// AddDocHandlersOf adds the documentation handlers of the documented App to this App router,
+// using the documented App DocConfig.
+// Use it to serve the documentation of a public API on an internal admin port:
+//
+//	admin.AddDocHandlersOf(public)
+func (a *App) AddDocHandlersOf(documented *App, middlewares ...handler.Middleware) error {
+	return a.addDocHandlers(a.router, documented.router, documented.docConfig, middlewares...)
+}
+
+// NewServer returns a new configured *http.Server serving the App router. (see NewServer)
+// The given Option override the App Option.
+func (a *App) NewServer(opts ...Option) *http.Server {
+	return NewServer(a.router, a.serverOptions(opts...)...)
+}
+
+// ServeAndHandleShutdown starts a *http.Server serving the App router. (see ServeAndHandleShutdown)
+// The given Option override the App Option.
+func (a *App) ServeAndHandleShutdown(opts ...Option) error {
+	return a.Serve(nil, opts...)
+}
+
+// Serve starts a *http.Server serving the App router, and calls ready with its bound address. (see Serve)
+// The given Option override the App Option.
+func (a *App) Serve(ready func(addr net.Addr), opts ...Option) error {
+	opts = a.serverOptions(opts...)
+
+	return serveRouter(a.newManager(opts...), a.router, ready, opts...)
+}
+
+// AddServer adds a *http.Server serving the App router to the lifecycle.Manager, under the given name. (see AddServer)
+// The App readiness endpoints fail as soon as the manager starts stopping.
+// The given Option override the App Option.
+func (a *App) AddServer(m *lifecycle.Manager, name string, opts ...Option) (net.Addr, error) {
+	opts = a.serverOptions(opts...)
+	a.handleStop(m, newOptions(opts...).Context())
+
+	return AddServer(m, name, a.router, opts...)
+}
+
+// AddTLSServer adds a *http.Server with TLS serving the App router to the lifecycle.Manager, under the given name.
+// (see AddTLSServer)
+// The given Option override the App Option.
+func (a *App) AddTLSServer(m *lifecycle.Manager, name, certCRT, certKey string, opts ...Option) (net.Addr, error) {
+	opts = a.serverOptions(opts...)
+	a.handleStop(m, newOptions(opts...).Context())
+
+	return AddTLSServer(m, name, a.router, certCRT, certKey, opts...)
+}
+
+// ServeAndHandleTLSShutdown starts a *http.Server with TLS serving the App router. (see ServeAndHandleTlSShutdown)
+// The given Option override the App Option.
+func (a *App) ServeAndHandleTLSShutdown(certCRT, certKey string, opts ...Option) error {
+	opts = a.serverOptions(opts...)
+
+	return serveRouterTLS(a.newManager(opts...), a.router, certCRT, certKey, opts...)
+}
+
+func (a *App) serverOptions(opts ...Option) []Option {
+	return append(append([]Option{}, a.options...), opts...)
+}
+
+func (a *App) group(router *mux.Router, prefix string, middlewares ...handler.Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:         a,
+		router:      router,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// addRoute registers the given engine on the router, and builds its middleware chain.
+func (a *App) addRoute(router *mux.Router, method, path string, engine *defaultHandleEngine) {
+	engine.app = a
+	if engine.static {
+		engine.checkStateless(method, path)
+	}
+
+	engine.getPlan()
+
+	router.Methods(method).
+		Path(path).
+		Handler(engine)
+}
+
+// addDocHandlers adds the documentation handlers of the documented router to the router r.
+func (a *App) addDocHandlers(r, documented *mux.Router, docConfig *openapi.DocConfig, middlewares ...handler.Middleware) error {
+	a.addRoute(r, http.MethodGet, docConfig.GetDocURI(), &defaultHandleEngine{
+		getHandler: staticFactory(openapi.NewRapiDocHandlerWithConfig(docConfig, middlewares...)),
+		static:     true,
+	})
+	a.addRoute(r, http.MethodGet, docConfig.GetAuthReceiverURI(), &defaultHandleEngine{
+		getHandler: staticFactory(openapi.NewRapiDocReceiverHandler(middlewares...)),
+		static:     true,
+	})
+	a.addRoute(r, http.MethodGet, docConfig.GetSpecOpenAPIURI(), &defaultHandleEngine{
+		getHandler: staticFactory(openapi.NewSpecOpenAPIHandlerWithConfig(documented, docConfig, middlewares...)),
+		static:     true,
+	})
+
+	return nil
+}
+
+// wrapErrors returns h wrapping all the errors it returns with the App error builders.
+// The App without globals wrap the errors returned by their handlers and by each of their middlewares.
+func (a *App) wrapErrors(h handler.Handler) handler.Handler {
+	if a.useGlobals {
+		return h
+	}
+
+	return errorBuilderHandler{app: a, handler: h}
+}
+
+// errorBuilderHandler is a handler.Handler wrapping all the errors returned by its handler
+// with the App error builders.
+type errorBuilderHandler struct {
+	app     *App
+	handler handler.Handler
+}
+
+// Serve implements the handler.Handler interface
+func (h errorBuilderHandler) Serve(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	resp, err := h.handler.Serve(w, r)
+	if err != nil {
+		return resp, h.app.WrapError(err)
+	}
+
+	return resp, nil
+}
+
+// staticFactory returns a handler.Factory always returning the given handler.
+func staticFactory(h handler.Handler) handler.Factory {
+	return func() handler.Handler {
+		return h
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+func TestApp(t *testing.T) {
+	errNotFound := fmt.Errorf("not found")
+
+	failingHandler := handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+		return nil, errNotFound
+	})
+
+	first := NewApp()
+	first.UseMiddlewares(headerMiddleware{value: "first"})
+	first.AddErrorBuilders(func(err error) errors.Error {
+		if err == errNotFound {
+			return errors.NotFound("not_found", "resource not found")
+		}
+
+		return nil
+	})
+	first.AddHandler(http.MethodGet, "/", failingHandler)
+
+	second := NewApp()
+	second.UseMiddlewares(headerMiddleware{value: "second"})
+	second.AddHandler(http.MethodGet, "/", failingHandler)
+
+	w := httptest.NewRecorder()
+	first.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, []string{"first"}, w.Header().Values("X-Middleware"))
+	assert.JSONEq(t, `{"kind":"not_found","message":"resource not found"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	second.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, []string{"second"}, w.Header().Values("X-Middleware"))
+	assert.JSONEq(t, `{"kind":"internal_error","message":"not found"}`, w.Body.String())
+
+	assert.Len(t, first.Middlewares(), 4)
+	assert.Len(t, second.Middlewares(), 4)
+}
+
+type failingMiddleware struct {
+	err error
+}
+
+func (m failingMiddleware) Wrap(handler.Handler) handler.Handler {
+	return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+		return nil, m.err
+	})
+}
+
+func TestApp_middlewareErrors(t *testing.T) {
+	errForbidden := fmt.Errorf("forbidden")
+
+	app := NewApp()
+	app.UseMiddlewares(failingMiddleware{err: errForbidden})
+	app.AddErrorBuilders(func(err error) errors.Error {
+		if err == errForbidden {
+			return errors.Forbidden("forbidden", "access denied")
+		}
+
+		return nil
+	})
+	app.AddHandlerFactory(http.MethodGet, "/", func() handler.Handler {
+		return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+			return nil, nil
+		})
+	})
+
+	w := httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"kind":"forbidden","message":"access denied"}`, w.Body.String())
+}
+
+type queryHandler struct {
+	params struct {
+		Value string `query:"value"`
+	}
+}
+
+func (h *queryHandler) Middlewares() []handler.Middleware {
+	return []handler.Middleware{middleware.QueryParameters{Parameters: &h.params}}
+}
+
+func (h *queryHandler) Serve(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return h.params.Value, nil
+}
+
+func TestApp_QueryDecoder(t *testing.T) {
+	first := NewApp()
+	first.QueryDecoder().IgnoreUnknownKeys(false)
+
+	second := NewApp()
+
+	for _, app := range []*App{first, second} {
+		app.AddHandlerFactory(http.MethodGet, "/", func() handler.Handler {
+			return &queryHandler{}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	first.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?value=a&unknown=b", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = httptest.NewRecorder()
+	second.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?value=a&unknown=b", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"a"`, w.Body.String())
+}
+
+func TestApp_rateLimitFactory(t *testing.T) {
+	app := NewApp()
+	app.UseMiddlewares(middleware.RateLimit{Limit: 1, Period: time.Minute})
+	app.AddHandlerFactory(http.MethodGet, "/", func() handler.Handler {
+		return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+			return nil, nil
+		})
+	})
+
+	w := httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestApp_concurrencyLimitFactory(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	var limiters sync.Map
+
+	app := NewApp()
+	app.UseMiddlewares(middleware.ConcurrencyLimit{
+		Options: middleware.ConcurrencyOptions{MaxInFlight: 1},
+		OnRouteLimiter: func(route *mux.Route, limiter *middleware.ConcurrencyLimiter) {
+			path, _ := route.GetPathTemplate()
+			limiters.Store(path, limiter)
+		},
+	})
+	app.AddHandlerFactory(http.MethodGet, "/slow", func() handler.Handler {
+		return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+			close(started)
+			<-unblock
+
+			return nil, nil
+		})
+	})
+	app.AddHandlerFactory(http.MethodGet, "/fast", func() handler.Handler {
+		return handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+			return nil, nil
+		})
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- w.Code
+	}()
+
+	<-started
+
+	w := httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Each route has its own limiter.
+	w = httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	close(unblock)
+	assert.Equal(t, http.StatusNoContent, <-done)
+
+	slow, ok := limiters.Load("/slow")
+	require.True(t, ok)
+	assert.Equal(t, middleware.ConcurrencyStats{Limit: 1, Served: 1, Shed: 1}, slow.(*middleware.ConcurrencyLimiter).Stats())
+}
+
+func TestAppAddServer(t *testing.T) {
+	public := NewApp(WithPort("0"), WithCORS(CORS{AllowedOrigins: []string{"https://example.com"}}))
+	public.UseMiddlewares(headerMiddleware{value: "public"})
+	public.AddHandler(http.MethodGet, "/users", documentedHandler{})
+
+	admin := NewApp(WithPort("0"))
+	admin.UseMiddlewares(headerMiddleware{value: "admin"})
+	admin.AddHealthHandlers()
+	require.NoError(t, admin.AddDocHandlersOf(public))
+
+	m := NewManager(WithStopSignals(syscall.SIGUSR1))
+
+	publicAddr, err := public.AddServer(m, "public")
+	require.NoError(t, err)
+
+	adminAddr, err := admin.AddServer(m, "admin")
+	require.NoError(t, err)
+
+	type result struct {
+		path    string
+		code    int
+		headers http.Header
+		body    string
+	}
+	results := make(chan result, 3)
+
+	m.Add("client", func(context.Context) error {
+		defer syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+		for _, url := range []string{
+			"http://" + publicAddr.String() + "/users",
+			"http://" + adminAddr.String() + DefaultReadinessURI,
+			"http://" + adminAddr.String() + openapi.Config.GetSpecOpenAPIURI(),
+		} {
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			req.Header.Set("Origin", "https://example.com")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			results <- result{path: req.URL.Path, code: resp.StatusCode, headers: resp.Header, body: string(body)}
+		}
+
+		return nil
+	}, nil)
+
+	require.NoError(t, m.Run())
+	close(results)
+
+	users := <-results
+	assert.Equal(t, http.StatusOK, users.code)
+	assert.Equal(t, []string{"public"}, users.headers.Values("X-Middleware"))
+	assert.Equal(t, "https://example.com", users.headers.Get("Access-Control-Allow-Origin"))
+
+	readiness := <-results
+	assert.Equal(t, http.StatusOK, readiness.code)
+	assert.Equal(t, []string{"admin"}, readiness.headers.Values("X-Middleware"))
+	assert.Equal(t, "*", readiness.headers.Get("Access-Control-Allow-Origin"))
+
+	spec := <-results
+	assert.Equal(t, http.StatusOK, spec.code)
+	assert.Contains(t, spec.body, `"/users"`)
+	assert.NotContains(t, spec.body, DefaultReadinessURI)
+}
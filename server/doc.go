@@ -18,10 +18,46 @@ You can see middleware implementation in the github.com/mwm-io/gapi/middleware p
 	var h SpecOpenAPIHandler
 	server.AddHandler(r, http.MethodGet, "/hello", h)
 
+	// Group your handlers under a common prefix with common middlewares.
+	admin := server.Group(r, "/admin", authMiddleware)
+	admin.AddHandler(http.MethodGet, "/hello", h)
+
+	// Add liveness and readiness endpoints.
+	server.AddHealthHandlers(r, server.NewCheck("database", db.PingContext))
+
+	// Serve the static files of a single page application, after the other handlers.
+	server.AddStaticHandler(r, "/", assets, server.WithSPAFallback())
+
 	// Add your server options here.
 	err := server.ServeAndHandleShutdown(r)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+The package-level functions share process-wide state (middleware.Defaults, openapi.Config...).
+If you need several routers with different configurations, use an App instead:
+
+	app := server.NewApp()
+	app.UseMiddlewares(authMiddleware)
+	app.AddHandler(http.MethodGet, "/hello", h)
+
+	err := app.ServeAndHandleShutdown()
+
+To serve several routers on different ports, or run background workers along with your servers,
+add them to a lifecycle.Manager: they share the signal handling and are stopped together.
+
+	public := server.NewApp(server.WithPort("8080"))
+	public.AddHandler(http.MethodGet, "/hello", h)
+
+	admin := server.NewApp(server.WithPort("9090"))
+	admin.AddHealthHandlers()
+	admin.AddDocHandlersOf(public)
+
+	m := server.NewManager()
+	public.AddServer(m, "public")
+	admin.AddServer(m, "admin")
+	m.Add("consumer", consumer.Run, nil)
+
+	err := m.Run()
 */
 package server
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// RouteGroup registers handlers under a common path prefix, with common middlewares and documentation.
+//
+// The group middlewares are added to the middleware.Defaults and to the handler middlewares
+// and sorted with them using handler.ByWeight.
+type RouteGroup struct {
+	app         *App
+	router      *mux.Router
+	prefix      string
+	middlewares []handler.Middleware
+	tags        []string
+	security    []map[string][]string
+}
+
+// Group returns a new RouteGroup registering its handlers on the given router,
+// prefixing their path with prefix and adding the given middlewares to them.
+//
+//	admin := server.Group(r, "/v1/admin", authMiddleware).WithTags("Admin")
+//	admin.AddHandler(http.MethodGet, "/users", listUsersHandler) // GET /v1/admin/users
+func Group(router *mux.Router, prefix string, middlewares ...handler.Middleware) *RouteGroup {
+	return defaultApp.group(router, prefix, middlewares...)
+}
+
+// Group returns a new RouteGroup nested in the current one:
+// its prefix, middlewares, tags and security are added to the current group ones.
+func (g *RouteGroup) Group(prefix string, middlewares ...handler.Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:         g.app,
+		router:      g.router,
+		prefix:      g.prefix + prefix,
+		middlewares: append(append([]handler.Middleware{}, g.middlewares...), middlewares...),
+		tags:        append([]string{}, g.tags...),
+		security:    append([]map[string][]string{}, g.security...),
+	}
+}
+
+// WithTags adds tags to every operation of the group, and return current instance.
+func (g *RouteGroup) WithTags(tags ...string) *RouteGroup {
+	g.tags = append(g.tags, tags...)
+	return g
+}
+
+// WithSecurity adds a security requirement to every operation of the group, and return current instance.
+func (g *RouteGroup) WithSecurity(name string, scopes ...string) *RouteGroup {
+	g.security = append(g.security, map[string][]string{name: scopes})
+	return g
+}
+
+// Prefix returns the path prefix of the group.
+func (g *RouteGroup) Prefix() string {
+	return g.prefix
+}
+
+// Router returns the mux.Router the group registers its handlers on.
+func (g *RouteGroup) Router() *mux.Router {
+	return g.router
+}
+
+// AddHandler register a new handler on a given method and path, prefixed by the group prefix.
+func (g *RouteGroup) AddHandler(method, path string, h handler.Handler) {
+	g.app.addRoute(g.router, method, g.prefix+path, &defaultHandleEngine{
+		getHandler:  staticFactory(h),
+		static:      true,
+		middlewares: g.middlewares,
+		docFuncs:    []func(builder *openapi.DocBuilder){g.doc},
+	})
+}
+
+// AddHandlerFactory register a new handler factory on a given method and path, prefixed by the group prefix.
+// (see AddHandlerFactory)
+func (g *RouteGroup) AddHandlerFactory(method, path string, f handler.Factory) {
+	g.app.addRoute(g.router, method, g.prefix+path, &defaultHandleEngine{
+		getHandler:  f,
+		middlewares: g.middlewares,
+		docFuncs:    []func(builder *openapi.DocBuilder){g.doc},
+	})
+}
+
+// doc adds the group tags and security to the operation.
+func (g *RouteGroup) doc(builder *openapi.DocBuilder) {
+	operation := builder.Operation()
+
+	var tags []string
+	for _, tag := range g.tags {
+		if !contains(operation.Tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	operation.WithTags(append(tags, operation.Tags...)...)
+
+	for _, requirement := range g.security {
+		for name, scopes := range requirement {
+			builder.WithSecurity(name, scopes...)
+		}
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
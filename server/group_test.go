@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+type headerMiddleware struct {
+	value  string
+	weight int
+}
+
+func (m headerMiddleware) Wrap(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		w.Header().Add("X-Middleware", m.value)
+		return h.Serve(w, r)
+	})
+}
+
+func (m headerMiddleware) Weight() int {
+	return m.weight
+}
+
+type documentedHandler struct {
+	handler.WithMiddlewares
+}
+
+func (h documentedHandler) Serve(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return "ok", nil
+}
+
+func (h documentedHandler) Doc(builder *openapi.DocBuilder) error {
+	builder.WithTags("Users")
+	return nil
+}
+
+func TestGroup(t *testing.T) {
+	r := NewMux()
+
+	admin := Group(r, "/v1/admin", headerMiddleware{value: "group", weight: 1}).
+		WithTags("Admin").
+		WithSecurity("bearer")
+	admin.AddHandler(http.MethodGet, "/users", documentedHandler{
+		WithMiddlewares: handler.WithMiddlewares{
+			MiddlewareList: []handler.Middleware{headerMiddleware{value: "handler", weight: -1}},
+		},
+	})
+	admin.Group("/nested", headerMiddleware{value: "nested", weight: 2}).
+		AddHandler(http.MethodGet, "/users", documentedHandler{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/users", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"handler", "group"}, w.Header().Values("X-Middleware"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/nested/users", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"group", "nested"}, w.Header().Values("X-Middleware"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	reflector := new(openapi3.Reflector)
+	assert.NoError(t, openapi.PopulateReflector(reflector, r, nil))
+
+	operation := reflector.Spec.Paths.MapOfPathItemValues["/v1/admin/users"].MapOfOperationValues["get"]
+	assert.Equal(t, []string{"Admin", "Users"}, operation.Tags)
+	assert.Equal(t, []map[string][]string{{"bearer": {}}}, operation.Security)
+}
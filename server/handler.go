@@ -2,7 +2,9 @@ package server
 
 import (
 	"net/http"
+	"reflect"
 	"sort"
+	"sync/atomic"
 
 	"github.com/gorilla/mux"
 
@@ -18,53 +20,196 @@ import (
 //   - you use a middleware for handle request params like middleware.BodyDecoder, middleware.PathParameters, etc.
 //   - you store properties in your handler struct during Serve process
 func AddHandlerFactory(router *mux.Router, method, path string, f handler.Factory) {
-	router.Methods(method).
-		Path(path).
-		Handler(defaultHandleEngine{
-			getHandler: f,
-		})
+	defaultApp.addRoute(router, method, path, &defaultHandleEngine{
+		getHandler: f,
+	})
 }
 
 // AddHandler register a new handler to the given mux router on a given method and path.
+// The handler is shared between all the requests: if its middlewares write request-scoped data into it,
+// use AddHandlerFactory instead. (see SetStrictMode)
 func AddHandler(router *mux.Router, method, path string, f handler.Handler) {
-	router.Methods(method).
-		Path(path).
-		Handler(defaultHandleEngine{
-			getHandler: func() handler.Handler {
-				return f
-			},
-		})
+	defaultApp.addRoute(router, method, path, &defaultHandleEngine{
+		getHandler: staticFactory(f),
+		static:     true,
+	})
 }
 
 type defaultHandleEngine struct {
+	app        *App
 	getHandler func() handler.Handler
+	// static is true if getHandler always returns the same handler: its middleware chain is built only once.
+	static bool
+	// newInstance returns the handler instance serving a request. If nil, getHandler is used.
+	// It is set when a static handler must be cloned for each request. (see App.SetStrictMode)
+	newInstance func() handler.Handler
+	// middlewares are added to the defaults and the handler middlewares. (see RouteGroup)
+	middlewares []handler.Middleware
+	// docFuncs are executed after the handler Doc func. (see RouteGroup)
+	docFuncs []func(builder *openapi.DocBuilder)
+
+	plan atomic.Pointer[handlerPlan]
+}
+
+// handlerPlan is the middleware chain of a route, computed once and reused for every request.
+// It is computed again if the App default middlewares change.
+type handlerPlan struct {
+	// version is the version of the App default middlewares the plan was built from.
+	version uint64
+	// defaults is the App default middleware list the plan was built from. (a copy of middleware.Defaults for the default App)
+	defaults []handler.Middleware
+	// middlewares is the sorted list of the App default middlewares and the route middlewares.
+	middlewares []handler.Middleware
+	// chain is the handler wrapped by all its middlewares. It is only set for static handlers.
+	chain handler.Handler
+	// order is the order in which the middlewares and the handler instance middlewares must be executed.
+	// It is only set for handler factories, once the first handler instance has been created.
+	order atomic.Pointer[middlewareOrder]
 }
 
-func (e defaultHandleEngine) getMiddlewareList(h handler.Handler) []handler.Middleware {
-	middlewareList := make([]handler.Middleware, len(middleware.Defaults))
-	copy(middlewareList, middleware.Defaults)
+// middlewareOrder is the sorted order of the plan middlewares and the middlewares of a handler instance.
+type middlewareOrder struct {
+	// weights are the weights of the handler instance middlewares the order was computed for.
+	weights []int
+	// indexes are the indexes of the middlewares to execute, in order.
+	// An index greater or equal to len(handlerPlan.middlewares) references a handler instance middleware.
+	indexes []int
+}
+
+func (e *defaultHandleEngine) getMiddlewareList(h handler.Handler) []handler.Middleware {
+	middlewareList := append(e.app.Middlewares(), e.middlewares...)
 
 	// if current handler have custom middlewares, add them to the list
 	if middlewareHandler, ok := h.(handler.MiddlewareAware); ok {
 		middlewareList = append(middlewareList, middlewareHandler.Middlewares()...)
 	}
 
-	sort.Sort(handler.ByWeight(middlewareList))
+	sort.Stable(handler.ByWeight(middlewareList))
 
 	return middlewareList
 }
 
-// ServeHTTP is the function called by mux when a request is handled
-func (e defaultHandleEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// get handler to serve
-	h := e.getHandler()
+// getPlan returns the current plan, building a new one if the App default middlewares changed.
+func (e *defaultHandleEngine) getPlan() *handlerPlan {
+	if plan := e.plan.Load(); plan != nil && e.isCurrent(plan) {
+		return plan
+	}
 
-	// get middleware list for this handler
-	middlewareList := e.getMiddlewareList(h)
+	version := e.app.middlewaresVersion.Load()
+	defaults := e.app.defaultMiddlewares()
+
+	if e.app.useGlobals {
+		// middleware.Defaults can be edited in place: the plan keeps the middlewares it has been built from.
+		defaults = append([]handler.Middleware(nil), defaults...)
+	}
+
+	plan := &handlerPlan{
+		version:     version,
+		defaults:    defaults,
+		middlewares: append(append(make([]handler.Middleware, 0, len(defaults)+len(e.middlewares)), defaults...), e.middlewares...),
+	}
+	sort.Stable(handler.ByWeight(plan.middlewares))
+
+	if e.static {
+		plan.chain = plan.wrapInstance(e, e.getHandler())
+	}
+
+	e.plan.Store(plan)
+
+	return plan
+}
+
+// wrapInstance wraps a handler instance with the plan middlewares and its own middlewares,
+// reusing the middleware order computed for the previous instances.
+func (p *handlerPlan) wrapInstance(e *defaultHandleEngine, h handler.Handler) handler.Handler {
+	var instanceMiddlewares []handler.Middleware
+	if middlewareHandler, ok := h.(handler.MiddlewareAware); ok {
+		instanceMiddlewares = middlewareHandler.Middlewares()
+	}
+
+	order := p.order.Load()
+	if order == nil || !order.matches(instanceMiddlewares) {
+		order = newMiddlewareOrder(p.middlewares, instanceMiddlewares)
+		p.order.Store(order)
+	}
+
+	h = e.app.wrapErrors(h)
 
 	// Execute all middleware with list order
-	for i := len(middlewareList) - 1; i >= 0; i-- {
-		h = middlewareList[i].Wrap(h)
+	for i := len(order.indexes) - 1; i >= 0; i-- {
+		index := order.indexes[i]
+		if index < len(p.middlewares) {
+			h = p.middlewares[index].Wrap(h)
+		} else {
+			h = instanceMiddlewares[index-len(p.middlewares)].Wrap(h)
+		}
+
+		// The errors returned by each middleware are wrapped too, before the outer middlewares write them.
+		h = e.app.wrapErrors(h)
+	}
+
+	return h
+}
+
+// newMiddlewareOrder sorts the given middlewares and instance middlewares, and returns their order.
+func newMiddlewareOrder(middlewares, instanceMiddlewares []handler.Middleware) *middlewareOrder {
+	all := append(append(make([]handler.Middleware, 0, len(middlewares)+len(instanceMiddlewares)), middlewares...), instanceMiddlewares...)
+
+	indexes := make([]int, len(all))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return handler.ByWeight{all[indexes[i]], all[indexes[j]]}.Less(0, 1)
+	})
+
+	weights := make([]int, len(instanceMiddlewares))
+	for i, m := range instanceMiddlewares {
+		weights[i] = weight(m)
+	}
+
+	return &middlewareOrder{
+		weights: weights,
+		indexes: indexes,
+	}
+}
+
+// matches returns true if the order was computed for middlewares with the same weights.
+func (o *middlewareOrder) matches(instanceMiddlewares []handler.Middleware) bool {
+	if len(o.weights) != len(instanceMiddlewares) {
+		return false
+	}
+
+	for i, m := range instanceMiddlewares {
+		if o.weights[i] != weight(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// instance returns the handler instance serving a request.
+func (e *defaultHandleEngine) instance() handler.Handler {
+	if e.newInstance != nil {
+		return e.newInstance()
+	}
+
+	return e.getHandler()
+}
+
+// ServeHTTP is the function called by mux when a request is handled
+func (e *defaultHandleEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	plan := e.getPlan()
+
+	h := plan.chain
+	if h == nil {
+		h = plan.wrapInstance(e, e.instance())
+	}
+
+	if e.app.queryDecoder != nil {
+		r = r.WithContext(middleware.WithQueryDecoder(r.Context(), e.app.queryDecoder))
 	}
 
 	// Ignore response & error : must be handle by response writer middleware
@@ -72,7 +217,7 @@ func (e defaultHandleEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // Doc is the function called by openapi during doc generation
-func (e defaultHandleEngine) Doc(builder *openapi.DocBuilder) error {
+func (e *defaultHandleEngine) Doc(builder *openapi.DocBuilder) error {
 	// get handler to serve
 	h := e.getHandler()
 
@@ -93,8 +238,108 @@ func (e defaultHandleEngine) Doc(builder *openapi.DocBuilder) error {
 
 	// Execute handler Doc func (if exist)
 	if documentedHandler, ok := h.(openapi.Documented); ok {
-		return documentedHandler.Doc(builder)
+		if err := documentedHandler.Doc(builder); err != nil {
+			return err
+		}
+	}
+
+	for _, docFunc := range e.docFuncs {
+		docFunc(builder)
+	}
+
+	return builder.Error()
+}
+
+// weight returns the weight of the given middleware. (see handler.SortableMiddleware)
+func weight(m handler.Middleware) int {
+	if sortable, ok := m.(handler.SortableMiddleware); ok {
+		return sortable.Weight()
+	}
+
+	return 0
+}
+
+// isCurrent returns true if the plan has been built from the current App default middlewares.
+// The default App also compares the middlewares of the middleware.Defaults slice,
+// as it can be replaced or edited without UseMiddlewares.
+func (e *defaultHandleEngine) isCurrent(plan *handlerPlan) bool {
+	if plan.version != e.app.middlewaresVersion.Load() {
+		return false
 	}
 
-	return nil
+	return !e.app.useGlobals || sameMiddlewares(plan.defaults, middleware.Defaults)
+}
+
+// sameMiddlewares returns true if a and b hold the same middlewares.
+func sameMiddlewares(a, b []handler.Middleware) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !sameValue(reflect.ValueOf(a[i]), reflect.ValueOf(b[i])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameValue returns true if a and b are equal values.
+// Unlike reflect.DeepEqual, the references (ie: maps, funcs, pointers) are only equal if they are the same,
+// so that the comparison is cheap and handles funcs.
+func sameValue(a, b reflect.Value) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	}
+
+	if !a.IsValid() {
+		return true
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+
+		return sameValue(a.Elem(), b.Elem())
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !sameValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !sameValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+
+		return true
+	case reflect.Slice:
+		return a.Len() == b.Len() && a.Pointer() == b.Pointer()
+	default:
+		// Maps, funcs, pointers, channels and unsafe pointers.
+		return a.Pointer() == b.Pointer()
+	}
 }
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+)
+
+type pathParamsHandler struct {
+	handler.WithMiddlewares
+
+	params struct {
+		ID string `path:"id"`
+	}
+}
+
+func newPathParamsHandler() handler.Handler {
+	h := &pathParamsHandler{}
+	h.MiddlewareList = []handler.Middleware{
+		middleware.PathParameters{Parameters: &h.params},
+	}
+
+	return h
+}
+
+func (h *pathParamsHandler) Serve(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return h.params.ID, nil
+}
+
+var helloHandler = handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return "hello", nil
+})
+
+func TestApp_UseMiddlewaresAfterAddHandler(t *testing.T) {
+	app := NewApp()
+	app.AddHandler(http.MethodGet, "/", helloHandler)
+	app.AddHandlerFactory(http.MethodGet, "/{id}", newPathParamsHandler)
+
+	app.UseMiddlewares(headerMiddleware{value: "late"})
+
+	w := httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"late"}, w.Header().Values("X-Middleware"))
+
+	w = httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/42", nil))
+	assert.Equal(t, []string{"late"}, w.Header().Values("X-Middleware"))
+	assert.Equal(t, `"42"`, w.Body.String())
+
+	// The chain is built again once the routes have been served.
+	app.UseMiddlewares(headerMiddleware{value: "later"})
+
+	w = httptest.NewRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"late", "later"}, w.Header().Values("X-Middleware"))
+}
+
+func TestAddHandler_defaultsEditedInPlace(t *testing.T) {
+	defaults := middleware.Defaults
+	middleware.Defaults = append(middleware.NewDefaults(), headerMiddleware{value: "first"})
+	defer func() { middleware.Defaults = defaults }()
+
+	r := NewMux()
+	AddHandler(r, http.MethodGet, "/", helloHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"first"}, w.Header().Values("X-Middleware"))
+
+	middleware.Defaults[len(middleware.Defaults)-1] = headerMiddleware{value: "second"}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"second"}, w.Header().Values("X-Middleware"))
+}
+
+func TestAddHandlerFactory_instanceMiddlewares(t *testing.T) {
+	calls := 0
+	factory := func() handler.Handler {
+		calls++
+
+		h := &documentedHandler{}
+		h.MiddlewareList = []handler.Middleware{headerMiddleware{value: "instance", weight: -1}}
+		if calls%2 == 0 {
+			h.MiddlewareList = append(h.MiddlewareList, headerMiddleware{value: "even", weight: 1})
+		}
+
+		return h
+	}
+
+	app := NewApp()
+	app.UseMiddlewares(headerMiddleware{value: "app"})
+	app.AddHandlerFactory(http.MethodGet, "/", factory)
+
+	for _, expected := range [][]string{
+		{"instance", "app"},
+		{"instance", "app", "even"},
+		{"instance", "app"},
+	} {
+		w := httptest.NewRecorder()
+		app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, expected, w.Header().Values("X-Middleware"))
+	}
+}
+
+// legacyHandleEngine reproduces the handler engine building the middleware chain on every request.
+type legacyHandleEngine struct {
+	getHandler handler.Factory
+}
+
+func (e legacyHandleEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := e.getHandler()
+
+	middlewareList := make([]handler.Middleware, len(middleware.Defaults))
+	copy(middlewareList, middleware.Defaults)
+
+	if middlewareHandler, ok := h.(handler.MiddlewareAware); ok {
+		middlewareList = append(middlewareList, middlewareHandler.Middlewares()...)
+	}
+
+	sort.Sort(handler.ByWeight(middlewareList))
+
+	for i := len(middlewareList) - 1; i >= 0; i-- {
+		h = middlewareList[i].Wrap(h)
+	}
+
+	_, _ = h.Serve(w, r)
+}
+
+func benchmarkRouter(b *testing.B, router http.Handler, path string) {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkAddHandler(b *testing.B) {
+	r := NewMux()
+	AddHandler(r, http.MethodGet, "/", helloHandler)
+
+	benchmarkRouter(b, r, "/")
+}
+
+func BenchmarkAddHandler_legacy(b *testing.B) {
+	r := NewMux()
+	r.Methods(http.MethodGet).Path("/").Handler(legacyHandleEngine{getHandler: staticFactory(helloHandler)})
+
+	benchmarkRouter(b, r, "/")
+}
+
+func BenchmarkAddHandlerFactory(b *testing.B) {
+	r := NewMux()
+	AddHandlerFactory(r, http.MethodGet, "/{id}", newPathParamsHandler)
+
+	benchmarkRouter(b, r, "/42")
+}
+
+func BenchmarkAddHandlerFactory_legacy(b *testing.B) {
+	r := NewMux()
+	r.Methods(http.MethodGet).Path("/{id}").Handler(legacyHandleEngine{getHandler: newPathParamsHandler})
+
+	benchmarkRouter(b, r, "/42")
+}
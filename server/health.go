@@ -0,0 +1,342 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+const (
+	// DefaultLivenessURI is the URI of the liveness endpoint.
+	DefaultLivenessURI = "/livez"
+	// DefaultReadinessURI is the URI of the readiness endpoint.
+	DefaultReadinessURI = "/readyz"
+	// DefaultCheckTimeout is the timeout of a Check that doesn't have one.
+	DefaultCheckTimeout = 3 * time.Second
+)
+
+// Health check status
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+	HealthStatusFailing  = "failing"
+)
+
+// Check is a health check.
+type Check interface {
+	// Name is the name of the check in the health report.
+	Name() string
+	// Timeout is the maximum duration of the check. If 0, DefaultCheckTimeout is used.
+	Timeout() time.Duration
+	// Critical indicates whether a failure of the check must fail the whole health report.
+	// A failing non critical check only degrades the report.
+	Critical() bool
+	// Check returns an error if the check fails.
+	// Its context is cancelled once Timeout is reached: the check is then reported as failing without waiting for it,
+	// so a check ignoring its context keeps running in the background until it returns.
+	Check(ctx context.Context) error
+}
+
+// CheckFunc is a Check calling a function.
+type CheckFunc struct {
+	CheckName    string
+	CheckTimeout time.Duration
+	IsCritical   bool
+	Func         func(ctx context.Context) error
+}
+
+// NewCheck returns a new critical Check calling f, with the DefaultCheckTimeout.
+func NewCheck(name string, f func(ctx context.Context) error) CheckFunc {
+	return CheckFunc{
+		CheckName:  name,
+		IsCritical: true,
+		Func:       f,
+	}
+}
+
+// SetTimeout set CheckTimeout and return current instance
+func (c CheckFunc) SetTimeout(timeout time.Duration) CheckFunc {
+	c.CheckTimeout = timeout
+	return c
+}
+
+// SetCritical set IsCritical and return current instance
+func (c CheckFunc) SetCritical(critical bool) CheckFunc {
+	c.IsCritical = critical
+	return c
+}
+
+// Name implements the Check interface
+func (c CheckFunc) Name() string {
+	return c.CheckName
+}
+
+// Timeout implements the Check interface
+func (c CheckFunc) Timeout() time.Duration {
+	return c.CheckTimeout
+}
+
+// Critical implements the Check interface
+func (c CheckFunc) Critical() bool {
+	return c.IsCritical
+}
+
+// Check implements the Check interface
+func (c CheckFunc) Check(ctx context.Context) error {
+	return c.Func(ctx)
+}
+
+// HealthReport is the response of the health endpoints.
+type HealthReport struct {
+	Status string        `json:"status" enum:"ok,degraded,failing"`
+	Checks []CheckReport `json:"checks"`
+}
+
+// StatusCode implements the middleware.WithStatusCode interface
+func (r HealthReport) StatusCode() int {
+	if r.Status == HealthStatusFailing {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
+
+// CheckReport is the result of a single Check.
+type CheckReport struct {
+	Name       string `json:"name"`
+	Status     string `json:"status" enum:"ok,failing"`
+	Critical   bool   `json:"critical"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Health runs health checks and serves the liveness and readiness endpoints.
+//
+// The liveness endpoint only runs the liveness checks: it should only fail if the process must be restarted.
+// The readiness endpoint runs the readiness checks, and fails as soon as its App starts stopping
+// (see NewManager and App.AddServer) or SetReady(false) has been called,
+// so that load balancers stop sending traffic before the server shuts down.
+type Health struct {
+	app             *App
+	mu              sync.RWMutex
+	livenessChecks  []Check
+	readinessChecks []Check
+	notReady        atomic.Bool
+}
+
+// NewHealth returns a new Health of the default App with the given readiness checks.
+// It is not ready as soon as a manager created with NewManager starts stopping.
+// (ie: StartProcessAndHandleStopSignals receives a stop signal)
+func NewHealth(checks ...Check) *Health {
+	return defaultApp.newHealth(checks...)
+}
+
+func (a *App) newHealth(checks ...Check) *Health {
+	return &Health{
+		app:             a,
+		readinessChecks: checks,
+	}
+}
+
+// AddReadinessChecks adds checks to the readiness endpoint.
+func (h *Health) AddReadinessChecks(checks ...Check) *Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.readinessChecks = append(h.readinessChecks, checks...)
+	return h
+}
+
+// AddLivenessChecks adds checks to the liveness endpoint.
+func (h *Health) AddLivenessChecks(checks ...Check) *Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.livenessChecks = append(h.livenessChecks, checks...)
+	return h
+}
+
+// SetReady marks the process as ready or not ready. A process that is not ready fails its readiness endpoint.
+func (h *Health) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Ready returns false if the process is not ready or is stopping.
+func (h *Health) Ready() bool {
+	return !h.notReady.Load() && !h.app.stopping.Load()
+}
+
+// Liveness runs the liveness checks and returns their report.
+func (h *Health) Liveness(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := h.livenessChecks
+	h.mu.RUnlock()
+
+	return runChecks(ctx, checks)
+}
+
+// Readiness runs the readiness checks and returns their report.
+// The report is failing if the process is not ready.
+func (h *Health) Readiness(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := h.readinessChecks
+	h.mu.RUnlock()
+
+	report := runChecks(ctx, checks)
+	if !h.Ready() {
+		report.Status = HealthStatusFailing
+	}
+
+	return report
+}
+
+// LivenessHandler returns a handler serving the liveness report.
+func (h *Health) LivenessHandler() handler.Handler {
+	return healthHandler{
+		report:      h.Liveness,
+		summary:     "Liveness",
+		description: "Fails if the process must be restarted",
+	}
+}
+
+// ReadinessHandler returns a handler serving the readiness report.
+func (h *Health) ReadinessHandler() handler.Handler {
+	return healthHandler{
+		report:      h.Readiness,
+		summary:     "Readiness",
+		description: "Fails if the process can't receive traffic, or is shutting down",
+	}
+}
+
+// AddHealthHandlers adds a liveness endpoint (DefaultLivenessURI) and a readiness endpoint (DefaultReadinessURI)
+// to the given router. The given checks are run by the readiness endpoint.
+// It returns the Health so you can add more checks or mark the process as not ready.
+func AddHealthHandlers(r *mux.Router, checks ...Check) *Health {
+	health := NewHealth(checks...)
+
+	AddHandler(r, http.MethodGet, DefaultLivenessURI, health.LivenessHandler())
+	AddHandler(r, http.MethodGet, DefaultReadinessURI, health.ReadinessHandler())
+
+	return health
+}
+
+// AddHealthHandlers adds the health endpoints to the App router. (see AddHealthHandlers)
+// The readiness endpoint fails as soon as a manager serving the App starts stopping. (see App.AddServer)
+func (a *App) AddHealthHandlers(checks ...Check) *Health {
+	health := a.newHealth(checks...)
+
+	a.AddHandler(http.MethodGet, DefaultLivenessURI, health.LivenessHandler())
+	a.AddHandler(http.MethodGet, DefaultReadinessURI, health.ReadinessHandler())
+
+	return health
+}
+
+// healthHandler is a handler.Handler serving a HealthReport.
+type healthHandler struct {
+	report      func(ctx context.Context) HealthReport
+	summary     string
+	description string
+}
+
+// Serve implements the handler.Handler interface
+func (h healthHandler) Serve(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return h.report(r.Context()), nil
+}
+
+// Doc implements the openapi.Documented interface
+func (h healthHandler) Doc(builder *openapi.DocBuilder) error {
+	builder.
+		WithSummary(h.summary).
+		WithDescription(h.description).
+		WithTags("Health").
+		WithResponse(HealthReport{}).
+		WithResponse(HealthReport{}, openapi.WithStatusCode(http.StatusServiceUnavailable), openapi.WithDescription("A critical check is failing"))
+
+	return builder.Error()
+}
+
+// runChecks runs all the given checks concurrently and returns their report.
+func runChecks(ctx context.Context, checks []Check) HealthReport {
+	report := HealthReport{
+		Status: HealthStatusOK,
+		Checks: make([]CheckReport, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, checkReport := range report.Checks {
+		if checkReport.Status == HealthStatusOK {
+			continue
+		}
+
+		if checkReport.Critical {
+			report.Status = HealthStatusFailing
+		} else if report.Status == HealthStatusOK {
+			report.Status = HealthStatusDegraded
+		}
+	}
+
+	return report
+}
+
+// runCheck runs the given check with its timeout.
+func runCheck(ctx context.Context, check Check) CheckReport {
+	timeout := check.Timeout()
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			// A panicking check fails instead of crashing the process.
+			if recovered := recover(); recovered != nil {
+				errCh <- fmt.Errorf("check panicked: %v", recovered)
+			}
+		}()
+
+		errCh <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	report := CheckReport{
+		Name:       check.Name(),
+		Status:     HealthStatusOK,
+		Critical:   check.Critical(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		report.Status = HealthStatusFailing
+		report.Error = err.Error()
+	}
+
+	return report
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	cacheErr := fmt.Errorf("cache unavailable")
+
+	app := NewApp()
+	health := app.AddHealthHandlers(
+		NewCheck("database", func(ctx context.Context) error { return nil }),
+		NewCheck("cache", func(ctx context.Context) error { return cacheErr }).SetCritical(false),
+	)
+
+	get := func(path string) (int, HealthReport) {
+		w := httptest.NewRecorder()
+		app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+		return w.Code, report
+	}
+
+	code, report := get(DefaultLivenessURI)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusOK, report.Status)
+
+	code, report = get(DefaultReadinessURI)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, CheckReport{Name: "database", Status: HealthStatusOK, Critical: true}, report.Checks[0])
+	assert.Equal(t, "cache unavailable", report.Checks[1].Error)
+
+	health.AddReadinessChecks(
+		NewCheck("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}).SetTimeout(10 * time.Millisecond),
+	)
+
+	code, report = get(DefaultReadinessURI)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusFailing, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[2].Error)
+
+	health.AddLivenessChecks(
+		NewCheck("panicking", func(ctx context.Context) error {
+			panic("boom")
+		}),
+	)
+
+	code, report = get(DefaultLivenessURI)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "check panicked: boom", report.Checks[0].Error)
+}
+
+func TestHealthStopSignal(t *testing.T) {
+	health := NewHealth()
+	// The default App stays not ready once stopped.
+	t.Cleanup(func() { defaultApp.stopping.Store(false) })
+
+	stopped := make(chan struct{})
+
+	var readyOnShutdown bool
+	err := StartProcessAndHandleStopSignals(
+		func() error {
+			if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+				return err
+			}
+
+			<-stopped
+			return nil
+		},
+		func(ctx context.Context) error {
+			readyOnShutdown = health.Ready()
+			close(stopped)
+			return nil
+		},
+		WithStopSignals(syscall.SIGUSR1),
+	)
+	require.NoError(t, err)
+	assert.False(t, readyOnShutdown)
+}
+
+func TestHealthAppStop(t *testing.T) {
+	app := NewApp(WithPort("0"))
+	health := app.AddHealthHandlers()
+
+	m := NewManager(WithStopSignals(syscall.SIGUSR2))
+	_, err := app.AddServer(m, "api")
+	require.NoError(t, err)
+
+	var readyOnShutdown bool
+	m.Add("process", func(ctx context.Context) error {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+			return err
+		}
+
+		<-ctx.Done()
+		return nil
+	}, func(ctx context.Context) error {
+		readyOnShutdown = health.Ready()
+		return nil
+	})
+
+	assert.True(t, health.Ready())
+	require.NoError(t, m.Run())
+	assert.False(t, readyOnShutdown)
+	assert.False(t, health.Ready())
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	gLog "github.com/mwm-io/gapi/log"
+	"github.com/mwm-io/gapi/websocket"
+)
+
+// InFlight is an http middleware tracking the requests being served.
+// Unlike http.Server.Shutdown, it also tracks the requests of hijacked connections (ie: websockets).
+type InFlight struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{}
+}
+
+// NewInFlight returns a new InFlight without any request.
+func NewInFlight() *InFlight {
+	idle := make(chan struct{})
+	close(idle)
+
+	return &InFlight{
+		idle: idle,
+	}
+}
+
+// Wrap returns a http.Handler tracking the requests served by h.
+func (t *InFlight) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.add(1)
+		defer t.add(-1)
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Count returns the number of requests being served.
+func (t *InFlight) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.count
+}
+
+// Wait waits until there is no request being served, or until ctx is done.
+func (t *InFlight) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *InFlight) add(delta int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count += delta
+
+	switch {
+	case t.count == 0:
+		close(t.idle)
+	case t.count == delta:
+		t.idle = make(chan struct{})
+	}
+}
+
+// drainedServer is a *http.Server drained on shutdown: it stops accepting connections,
+// waits for its in-flight requests, and cancels their context if they are not done before the stop timeout.
+type drainedServer struct {
+	*http.Server
+	name           string
+	inFlight       *InFlight
+	cancelRequests context.CancelFunc
+}
+
+func newDrainedServer(name string, r *mux.Router, opts ...Option) *drainedServer {
+	c := newOptions(opts...)
+
+	inFlight := c.InFlight()
+	if inFlight == nil {
+		inFlight = NewInFlight()
+		opts = append(opts, WithInFlight(inFlight))
+	}
+
+	srv := NewServer(r, opts...)
+
+	ctx, cancel := context.WithCancel(c.Context())
+	baseCtx := websocket.WithServerContext(ctx, ctx)
+	srv.BaseContext = func(net.Listener) context.Context {
+		return baseCtx
+	}
+
+	return &drainedServer{
+		Server:         srv,
+		name:           name,
+		inFlight:       inFlight,
+		cancelRequests: cancel,
+	}
+}
+
+// Shutdown drains the server until ctx is done. The context of the requests is always cancelled when it returns.
+func (s *drainedServer) Shutdown(ctx context.Context) error {
+	defer s.cancelRequests()
+
+	gLog.Info(ctx).With(zap.String("server", s.name)).
+		LogMsg("%s: stop accepting connections, waiting for %d in-flight requests", s.name, s.inFlight.Count())
+
+	err := s.Server.Shutdown(ctx)
+	if err == nil {
+		err = s.inFlight.Wait(ctx)
+	}
+
+	if err != nil {
+		gLog.Warn(ctx).With(zap.String("server", s.name)).
+			LogMsg("%s: %d in-flight requests not completed before the stop timeout: cancelling them", s.name, s.inFlight.Count())
+
+		_ = s.Server.Close()
+
+		return err
+	}
+
+	gLog.Info(ctx).With(zap.String("server", s.name)).LogMsg("%s: drained", s.name)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestAddServerDrain(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	app := NewApp(WithPort("0"))
+	app.AddHealthHandlers()
+	app.AddHandler(http.MethodGet, "/slow", handler.Func(func(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+		close(started)
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+
+		return nil, nil
+	}))
+
+	inFlight := NewInFlight()
+	m := NewManager(
+		WithStopSignals(syscall.SIGUSR1),
+		WithDrainGracePeriod(50*time.Millisecond),
+		WithStopTimeout(50*time.Millisecond),
+	)
+
+	addr, err := app.AddServer(m, "api", WithInFlight(inFlight))
+	require.NoError(t, err)
+
+	readiness := make(chan int, 1)
+	m.Add("client", func(context.Context) error {
+		go func() {
+			resp, err := http.Get("http://" + addr.String() + "/slow")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+
+		<-started
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+			return err
+		}
+
+		// Wait for the stop signal to be handled: requests are still served during the grace period.
+		time.Sleep(10 * time.Millisecond)
+
+		resp, err := http.Get("http://" + addr.String() + DefaultReadinessURI)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		readiness <- resp.StatusCode
+		return nil
+	}, nil)
+
+	err = m.Run()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, http.StatusServiceUnavailable, <-readiness)
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+	assert.Eventually(t, func() bool {
+		return inFlight.Count() == 0
+	}, time.Second, time.Millisecond)
+}
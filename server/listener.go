@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemd socket activation environment variables and first file descriptor.
+// (see https://www.freedesktop.org/software/systemd/man/sd_listen_fds.html)
+const (
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+	listenFDsStart   = 3
+)
+
+// newListener returns the listener of the server, by order of priority:
+//   - the listener given with WithListener
+//   - the socket passed by systemd, if WithSocketActivation is used and the process has been socket activated
+//   - a Unix domain socket, if WithUnixSocket is used
+//   - a TCP listener on the server address
+func newListener(c serverOptions) (net.Listener, error) {
+	if c.Listener() != nil {
+		return c.Listener(), nil
+	}
+
+	if name, ok := c.SocketActivation(); ok {
+		ln, err := activationListener(name)
+		if err != nil || ln != nil {
+			return ln, err
+		}
+	}
+
+	if path, mode := c.UnixSocket(); path != "" {
+		return listenUnix(path, mode)
+	}
+
+	return net.Listen("tcp", c.Addr())
+}
+
+// listenUnix listens on a Unix domain socket at the given path, with the given file mode.
+// A stale socket left by a previous process is removed.
+func listenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("failed to change socket %s mode: %w", path, err)
+		}
+	}
+
+	return ln, nil
+}
+
+var (
+	activationOnce      sync.Once
+	activationListeners []net.Listener
+	activationNames     []string
+	activationErr       error
+)
+
+// ActivationListeners returns the listeners passed by systemd to the process (socket activation),
+// in the order of the systemd.socket configuration.
+// It returns no listener if the process has not been socket activated.
+func ActivationListeners() ([]net.Listener, error) {
+	activationOnce.Do(loadActivationListeners)
+
+	return activationListeners, activationErr
+}
+
+// activationListener returns the listener passed by systemd with the given name (see FileDescriptorName in systemd.socket),
+// or the first one if name is empty.
+// It returns nil if the process has not been socket activated.
+func activationListener(name string) (net.Listener, error) {
+	listeners, err := ActivationListeners()
+	if err != nil || len(listeners) == 0 {
+		return nil, err
+	}
+
+	if name == "" {
+		return listeners[0], nil
+	}
+
+	for i, n := range activationNames {
+		if n == name {
+			return listeners[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no socket named %s passed by systemd", name)
+}
+
+func loadActivationListeners() {
+	pid, err := strconv.Atoi(os.Getenv(listenPIDEnv))
+	if err != nil || pid != os.Getpid() {
+		return
+	}
+
+	count, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	if err != nil || count <= 0 {
+		return
+	}
+
+	names := strings.Split(os.Getenv(listenFDNamesEnv), ":")
+
+	// Child processes must not consider they have been socket activated.
+	_ = os.Unsetenv(listenPIDEnv)
+	_ = os.Unsetenv(listenFDsEnv)
+	_ = os.Unsetenv(listenFDNamesEnv)
+
+	for i := 0; i < count; i++ {
+		var name string
+		if i < len(names) {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			activationErr = fmt.Errorf("socket %d passed by systemd is not a listener: %w", i, err)
+			return
+		}
+
+		activationListeners = append(activationListeners, ln)
+		activationNames = append(activationNames, name)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestServe(t *testing.T) {
+	r := NewMux()
+	AddHandler(r, http.MethodGet, "/", handler.Func(func(http.ResponseWriter, *http.Request) (interface{}, error) {
+		return nil, nil
+	}))
+
+	socketPath := filepath.Join(t.TempDir(), "gapi.sock")
+
+	tests := map[string]struct {
+		opts   []Option
+		client func(addr net.Addr) *http.Client
+	}{
+		"ephemeral port": {
+			opts: []Option{WithPort("0")},
+			client: func(net.Addr) *http.Client {
+				return http.DefaultClient
+			},
+		},
+		"unix socket": {
+			opts: []Option{WithUnixSocket(socketPath, 0o600)},
+			client: func(addr net.Addr) *http.Client {
+				return &http.Client{
+					Transport: &http.Transport{
+						DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+							return (&net.Dialer{}).DialContext(ctx, "unix", addr.String())
+						},
+					},
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			type result struct {
+				addr       net.Addr
+				statusCode int
+			}
+			results := make(chan result, 1)
+
+			err := Serve(r, func(addr net.Addr) {
+				defer syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+
+				host := addr.String()
+				if addr.Network() == "unix" {
+					host = "unix"
+				}
+
+				var statusCode int
+				if resp, err := test.client(addr).Get("http://" + host + "/"); err == nil {
+					resp.Body.Close()
+					statusCode = resp.StatusCode
+				}
+
+				results <- result{addr: addr, statusCode: statusCode}
+			}, append(test.opts, WithStopSignals(syscall.SIGUSR2))...)
+
+			require.NoError(t, err)
+
+			res := <-results
+			assert.Equal(t, http.StatusNoContent, res.statusCode)
+			assert.NotEqual(t, ":0", res.addr.String())
+		})
+	}
+
+	_, err := os.Stat(socketPath)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
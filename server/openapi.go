@@ -1,22 +1,20 @@
 package server
 
 import (
-	"net/http"
-
 	"github.com/gorilla/mux"
 
 	"github.com/mwm-io/gapi/handler"
-	"github.com/mwm-io/gapi/openapi"
 )
 
 // AddDocHandlers will add the necessary handlers to serve a rapidoc endpoint:
 // 2 endpoints to serve rapidoc.html and oauth-receiver.html from rapidoc
 // and one endpoint to serve the json openapi definition of your API.
 func AddDocHandlers(r *mux.Router, middlewares ...handler.Middleware) error {
+	return defaultApp.addDocHandlers(r, r, defaultApp.docConfig, middlewares...)
+}
 
-	AddHandler(r, http.MethodGet, openapi.Config.GetDocURI(), openapi.NewRapiDocHandler(middlewares...))
-	AddHandler(r, http.MethodGet, openapi.Config.GetAuthReceiverURI(), openapi.NewRapiDocReceiverHandler(middlewares...))
-	AddHandler(r, http.MethodGet, openapi.Config.GetSpecOpenAPIURI(), openapi.NewSpecOpenAPIHandler(r, middlewares...))
-
-	return nil
+// AddDocHandlersOf adds the documentation handlers of the documented router to the router r.
+// Use it to serve the documentation of a public API on an internal admin port. (see AddServer)
+func AddDocHandlersOf(r, documented *mux.Router, middlewares ...handler.Middleware) error {
+	return defaultApp.addDocHandlers(r, documented, defaultApp.docConfig, middlewares...)
 }
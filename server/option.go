@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io/fs"
+	"net"
 	"os"
 	"syscall"
 	"time"
@@ -46,6 +49,25 @@ func WithStopTimeout(d time.Duration) Option {
 	}
 }
 
+// WithDrainGracePeriod sets the time to wait between the stop signal and the shutdown of the servers.
+// During this period, the readiness endpoints fail (see AddHealthHandlers) but requests are still served,
+// giving load balancers (ie: Kubernetes endpoints controller) the time to stop routing traffic to the server.
+// The StopTimeout starts after the grace period.
+// By default, there is no grace period.
+func WithDrainGracePeriod(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.drainGracePeriod = d
+	}
+}
+
+// WithInFlight tracks the requests served by the server with the given InFlight. (ie: to expose the number of in-flight requests)
+// By default, servers added with AddServer track their requests to drain them on shutdown.
+func WithInFlight(inFlight *InFlight) Option {
+	return func(config *serverOptions) {
+		config.inFlight = inFlight
+	}
+}
+
 // WithStopSignals specify on which os.Signal we must shut down the server.
 // By default, os.Interrupt, syscall.SIGINT and syscall.SIGTERM
 func WithStopSignals(signals ...os.Signal) Option {
@@ -73,13 +95,144 @@ func WithContext(ctx context.Context) Option {
 	}
 }
 
+// WithReadTimeout sets the maximum duration for reading the entire request, including the body.
+// By default, there is no timeout.
+func WithReadTimeout(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.readTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the amount of time allowed to read the request headers.
+// By default, 10 seconds, to protect the server against slow clients (slowloris attacks).
+// A negative duration disables the timeout.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.readHeaderTimeout = &d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response.
+// By default, there is no timeout, as it would also limit streamed responses.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum amount of time to wait for the next request when keep-alives are enabled.
+// By default, the read timeout is used.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.idleTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum number of bytes the server will read parsing the request headers.
+// By default, http.DefaultMaxHeaderBytes (1 MB).
+func WithMaxHeaderBytes(n int) Option {
+	return func(config *serverOptions) {
+		config.maxHeaderBytes = n
+	}
+}
+
+// WithH2C specify if the server must accept HTTP/2 requests without TLS (h2c), along with HTTP/1 requests.
+// Use it when the server runs behind a load balancer terminating TLS and talking HTTP/2 to its backends.
+// By default, h2c is disabled.
+func WithH2C(enabled bool) Option {
+	return func(config *serverOptions) {
+		config.h2c = enabled
+	}
+}
+
+// WithTLSConfig sets the base TLS configuration of the server. (ie: MinVersion, CipherSuites, ClientAuth...)
+// The certificate given to ServeAndHandleTlSShutdown overrides its certificates.
+// By default, only TLS 1.2 and above are accepted.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(config *serverOptions) {
+		config.tlsConfig = tlsConfig
+	}
+}
+
+// WithClientCA enables mutual TLS: clients must present a certificate signed by one of the CA of the given PEM bundle.
+// The verified client identity is available in the request context. (see PeerIdentityFromContext)
+// The client certificate is required, unless another ClientAuth is set using WithTLSConfig.
+func WithClientCA(caFile string) Option {
+	return func(config *serverOptions) {
+		config.clientCAFile = caFile
+	}
+}
+
+// WithCertReloadInterval sets the interval at which the certificate and key files are checked for changes.
+// The certificate is also reloaded when the process receives a SIGHUP.
+// By default, 10 seconds. A negative duration disables the files check.
+func WithCertReloadInterval(d time.Duration) Option {
+	return func(config *serverOptions) {
+		config.certReloadInterval = &d
+	}
+}
+
+// WithHTTPRedirect starts a plain HTTP server on the given port along with the TLS server,
+// redirecting all the requests to https.
+// By default, no HTTP server is started.
+func WithHTTPRedirect(port string) Option {
+	return func(config *serverOptions) {
+		config.httpRedirectPort = port
+	}
+}
+
+// WithListener sets the listener the server accepts connections on, instead of listening on the server port.
+// (ie: a listener on an ephemeral port in tests)
+func WithListener(ln net.Listener) Option {
+	return func(config *serverOptions) {
+		config.listener = ln
+	}
+}
+
+// WithUnixSocket makes the server listen on a Unix domain socket at the given path instead of the server port.
+// A stale socket file is removed before listening, and the socket file mode is set to mode if not 0.
+func WithUnixSocket(path string, mode fs.FileMode) Option {
+	return func(config *serverOptions) {
+		config.unixSocketPath = path
+		config.unixSocketMode = mode
+	}
+}
+
+// WithSocketActivation makes the server accept connections on a socket passed by systemd (socket activation).
+// The name is the FileDescriptorName of the socket in the systemd.socket configuration: if empty, the first socket is used.
+// If the process has not been socket activated, the server falls back to its other listen options.
+func WithSocketActivation(name string) Option {
+	return func(config *serverOptions) {
+		config.socketActivation = true
+		config.socketActivationName = name
+	}
+}
+
 type serverOptions struct {
 	withoutStrictSlash bool
 	port               string
 	cors               *CORS
 	stopTimeout        *time.Duration
+	drainGracePeriod   time.Duration
+	inFlight           *InFlight
 	stopSignals        []os.Signal
 	context            context.Context
+	readTimeout        time.Duration
+	readHeaderTimeout  *time.Duration
+	writeTimeout       time.Duration
+	idleTimeout        time.Duration
+	maxHeaderBytes     int
+	h2c                bool
+	tlsConfig          *tls.Config
+	clientCAFile       string
+	certReloadInterval *time.Duration
+	httpRedirectPort   string
+
+	listener             net.Listener
+	unixSocketPath       string
+	unixSocketMode       fs.FileMode
+	socketActivation     bool
+	socketActivationName string
 }
 
 func newOptions(opts ...Option) serverOptions {
@@ -131,6 +284,16 @@ func (c serverOptions) StopTimeout() time.Duration {
 	return 3 * time.Second
 }
 
+// DrainGracePeriod returns the time to wait between the stop signal and the shutdown of the servers.
+func (c serverOptions) DrainGracePeriod() time.Duration {
+	return c.drainGracePeriod
+}
+
+// InFlight returns the InFlight tracking the requests served by the server.
+func (c serverOptions) InFlight() *InFlight {
+	return c.inFlight
+}
+
 // StopSignals returns the signals to listen to for shutting down the server.
 func (c serverOptions) StopSignals() []os.Signal {
 	if len(c.stopSignals) != 0 {
@@ -153,3 +316,86 @@ func (c serverOptions) Context() context.Context {
 
 	return context.Background()
 }
+
+// ReadTimeout returns the maximum duration for reading the entire request.
+func (c serverOptions) ReadTimeout() time.Duration {
+	return c.readTimeout
+}
+
+// ReadHeaderTimeout returns the amount of time allowed to read the request headers.
+func (c serverOptions) ReadHeaderTimeout() time.Duration {
+	if c.readHeaderTimeout == nil {
+		return 10 * time.Second
+	}
+
+	if *c.readHeaderTimeout < 0 {
+		return 0
+	}
+
+	return *c.readHeaderTimeout
+}
+
+// WriteTimeout returns the maximum duration before timing out writes of the response.
+func (c serverOptions) WriteTimeout() time.Duration {
+	return c.writeTimeout
+}
+
+// IdleTimeout returns the maximum amount of time to wait for the next request.
+func (c serverOptions) IdleTimeout() time.Duration {
+	return c.idleTimeout
+}
+
+// MaxHeaderBytes returns the maximum number of bytes of the request headers.
+func (c serverOptions) MaxHeaderBytes() int {
+	return c.maxHeaderBytes
+}
+
+// H2C returns true if the server accepts HTTP/2 requests without TLS.
+func (c serverOptions) H2C() bool {
+	return c.h2c
+}
+
+// TLSConfig returns a copy of the base TLS configuration.
+func (c serverOptions) TLSConfig() *tls.Config {
+	if c.tlsConfig != nil {
+		return c.tlsConfig.Clone()
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// ClientCAFile returns the path of the CA bundle used to verify client certificates.
+func (c serverOptions) ClientCAFile() string {
+	return c.clientCAFile
+}
+
+// CertReloadInterval returns the interval at which the certificate files are checked for changes.
+func (c serverOptions) CertReloadInterval() time.Duration {
+	if c.certReloadInterval != nil {
+		return *c.certReloadInterval
+	}
+
+	return 10 * time.Second
+}
+
+// HTTPRedirectPort returns the port of the HTTP server redirecting to https.
+func (c serverOptions) HTTPRedirectPort() string {
+	return c.httpRedirectPort
+}
+
+// Listener returns the listener set with WithListener.
+func (c serverOptions) Listener() net.Listener {
+	return c.listener
+}
+
+// UnixSocket returns the path and file mode of the Unix domain socket to listen on.
+func (c serverOptions) UnixSocket() (string, fs.FileMode) {
+	return c.unixSocketPath, c.unixSocketMode
+}
+
+// SocketActivation returns the name of the systemd socket to listen on, and whether socket activation is enabled.
+func (c serverOptions) SocketActivation() (string, bool) {
+	return c.socketActivationName, c.socketActivation
+}
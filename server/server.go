@@ -2,13 +2,18 @@ package server
 
 import (
 	"context"
+	"log"
 	"net"
 	"net/http"
-	"os"
-	"os/signal"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/mwm-io/gapi/lifecycle"
+	gLog "github.com/mwm-io/gapi/log"
 )
 
 // NewServer returns a new configured *http.Server, using an existing mux.Router.
@@ -18,19 +23,50 @@ func NewServer(r *mux.Router, opts ...Option) *http.Server {
 
 	r = r.StrictSlash(c.StrictSlash())
 
+	var h http.Handler = handlers.CORS(
+		handlers.AllowedOrigins(c.CORS().AllowedOrigins),
+		handlers.AllowedHeaders(c.CORS().AllowedHeaders),
+		handlers.AllowedMethods(c.CORS().AllowedMethods),
+	)(r)
+
+	h = withPeerIdentity(h)
+
+	if c.InFlight() != nil {
+		h = c.InFlight().Wrap(h)
+	}
+
+	if c.H2C() {
+		h = h2c.NewHandler(h, &http2.Server{
+			IdleTimeout: c.IdleTimeout(),
+		})
+	}
+
 	return &http.Server{
-		Addr: c.Addr(),
-		Handler: handlers.CORS(
-			handlers.AllowedOrigins(c.CORS().AllowedOrigins),
-			handlers.AllowedHeaders(c.CORS().AllowedHeaders),
-			handlers.AllowedMethods(c.CORS().AllowedMethods),
-		)(r),
+		Addr:              c.Addr(),
+		Handler:           h,
+		ReadTimeout:       c.ReadTimeout(),
+		ReadHeaderTimeout: c.ReadHeaderTimeout(),
+		WriteTimeout:      c.WriteTimeout(),
+		IdleTimeout:       c.IdleTimeout(),
+		MaxHeaderBytes:    c.MaxHeaderBytes(),
+		ErrorLog:          newErrorLog(c.Context()),
 		BaseContext: func(listener net.Listener) context.Context {
 			return c.Context()
 		},
 	}
 }
 
+// newErrorLog returns a *log.Logger writing the http.Server errors
+// (ie: TLS handshake errors, panics in handlers...) into the gapi logger.
+func newErrorLog(ctx context.Context) *log.Logger {
+	errorLog, err := zap.NewStdLogAt(gLog.Logger(ctx).Named("http"), zap.ErrorLevel)
+	if err != nil {
+		return nil
+	}
+
+	return errorLog
+}
+
 // NewMux returns a new *mux.Router.
 func NewMux() *mux.Router {
 	return mux.NewRouter()
@@ -39,64 +75,200 @@ func NewMux() *mux.Router {
 // ServeAndHandleShutdown start a *http.Server with the default configuration (overridden by the given Option)
 // This function lock your program until a signal stopping your program is received. (see WithStopSignals)
 func ServeAndHandleShutdown(r *mux.Router, opts ...Option) error {
-	srv := NewServer(r, opts...)
+	return Serve(r, nil, opts...)
+}
 
-	return StartProcessAndHandleStopSignals(
-		func() error {
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				return err
-			}
+// Serve start a *http.Server with the default configuration (overridden by the given Option),
+// and calls ready with the address the server is bound to, once it is listening.
+// (ie: to learn the port chosen by the system when listening on port "0")
+// By default, the server listens on its port: see WithListener, WithUnixSocket and WithSocketActivation to change it.
+// This function lock your program until a signal stopping your program is received. (see WithStopSignals)
+func Serve(r *mux.Router, ready func(addr net.Addr), opts ...Option) error {
+	return serveRouter(NewManager(opts...), r, ready, opts...)
+}
 
+// serveRouter serves the given router with the given manager. (see Serve)
+func serveRouter(m *lifecycle.Manager, r *mux.Router, ready func(addr net.Addr), opts ...Option) error {
+	addr, err := AddServer(m, "http", r, opts...)
+	if err != nil {
+		return err
+	}
+
+	if ready != nil {
+		m.Add("ready", func(context.Context) error {
+			ready(addr)
 			return nil
-		},
-		func(ctx context.Context) error {
-			return srv.Shutdown(ctx)
-		},
-	)
+		}, nil)
+	}
+
+	return run(m)
 }
 
 // ServeAndHandleTlSShutdown start a *http.Server with TLS and the default configuration (overridden by the given Option)
+// The certificate and key files are reloaded when they change, or when the process receives a SIGHUP.
+// (see WithTLSConfig, WithClientCA, WithCertReloadInterval and WithHTTPRedirect)
+// If certCRT and certKey are empty, the certificates of the WithTLSConfig configuration are used.
 // This function lock your program until a signal stopping your program is received. (see WithStopSignals)
 func ServeAndHandleTlSShutdown(r *mux.Router, certCRT, certKey string, opts ...Option) error {
-	srv := NewServer(r, opts...)
+	return serveRouterTLS(NewManager(opts...), r, certCRT, certKey, opts...)
+}
 
-	return StartProcessAndHandleStopSignals(
-		func() error {
-			if err := srv.ListenAndServeTLS(certCRT, certKey); err != nil && err != http.ErrServerClosed {
-				return err
-			}
+// serveRouterTLS serves the given router with TLS with the given manager. (see ServeAndHandleTlSShutdown)
+func serveRouterTLS(m *lifecycle.Manager, r *mux.Router, certCRT, certKey string, opts ...Option) error {
+	if _, err := AddTLSServer(m, "https", r, certCRT, certKey, opts...); err != nil {
+		return err
+	}
+
+	return run(m)
+}
 
+// AddServer adds a *http.Server serving the given router to the lifecycle.Manager (see NewManager), under the given name.
+// Use it to serve several routers on different ports in the same program (ie: a public API and an internal admin port),
+// each with its own Option (port, CORS, timeouts...), sharing the signal handling and the graceful shutdown of the manager.
+//
+// On shutdown, the server is drained: it stops accepting connections and waits for its in-flight requests
+// until the StopTimeout, then cancels their context. (see WithDrainGracePeriod)
+//
+// The server starts listening immediately, so listen errors are returned before the manager runs,
+// and the returned address is the one the server is bound to.
+func AddServer(m *lifecycle.Manager, name string, r *mux.Router, opts ...Option) (net.Addr, error) {
+	c := newOptions(opts...)
+	srv := newDrainedServer(name, r, opts...)
+
+	ln, err := newListener(c)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Add(name, func(context.Context) error {
+		return serve(srv.Server, ln)
+	}, srv.Shutdown)
+
+	return ln.Addr(), nil
+}
+
+// AddTLSServer adds a *http.Server with TLS serving the given router to the lifecycle.Manager, under the given name.
+// (see AddServer and ServeAndHandleTlSShutdown)
+func AddTLSServer(m *lifecycle.Manager, name string, r *mux.Router, certCRT, certKey string, opts ...Option) (net.Addr, error) {
+	c := newOptions(opts...)
+	srv := newDrainedServer(name, r, opts...)
+
+	tlsConfig, reloader, err := newTLSConfig(c, certCRT, certKey)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.TLSConfig = tlsConfig
+
+	ln, err := newListener(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HTTPRedirectPort() != "" {
+		redirectSrv := newRedirectServer(c, c.HTTPRedirectPort())
+		m.Add(name+"-redirect", func(context.Context) error {
+			return serve(redirectSrv, nil)
+		}, redirectSrv.Shutdown)
+	}
+
+	m.Add(name, func(context.Context) error {
+		if err := srv.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+
+		return nil
+	}, srv.Shutdown)
+
+	if reloader != nil {
+		m.Add(name+"-certificate-reloader", func(ctx context.Context) error {
+			reloader.watch(ctx, c.CertReloadInterval())
 			return nil
-		},
-		func(ctx context.Context) error {
-			return srv.Shutdown(ctx)
-		},
-	)
+		}, nil)
+	}
+
+	return ln.Addr(), nil
 }
 
 // StartProcessAndHandleStopSignals starts the given process and listen for os stop signals to stop it,
 // executing the shutdown function.
+// As soon as a stop signal is received, the readiness endpoints (see AddHealthHandlers) start failing,
+// before the shutdown function is executed.
 // It can also take Option to customize StopSignals, Context, and StopTimeout.
+//
+// To run several processes, use NewManager instead.
 func StartProcessAndHandleStopSignals(process func() error, shutdown func(ctx context.Context) error, opts ...Option) error {
+	m := NewManager(opts...)
+	m.Add("process", func(context.Context) error {
+		return process()
+	}, shutdown)
+
+	return run(m)
+}
+
+// NewManager returns a new lifecycle.Manager configured with the StopSignals, Context, StopTimeout and DrainGracePeriod Option.
+// As soon as it starts stopping, the readiness endpoints of the default App (see AddHealthHandlers) start failing,
+// and the servers are shut down after the drain grace period.
+func NewManager(opts ...Option) *lifecycle.Manager {
+	return defaultApp.newManager(opts...)
+}
+
+// newManager returns a new lifecycle.Manager (see NewManager) making the readiness endpoints of the App fail
+// as soon as it starts stopping.
+func (a *App) newManager(opts ...Option) *lifecycle.Manager {
 	c := newOptions(opts...)
 
-	processErrCh := make(chan error, 1)
-	go func() {
-		processErrCh <- process()
-	}()
+	m := lifecycle.NewManager(
+		lifecycle.WithStopSignals(c.StopSignals()...),
+		lifecycle.WithStopTimeout(c.StopTimeout()),
+		lifecycle.WithGracePeriod(c.DrainGracePeriod()),
+		lifecycle.WithContext(c.Context()),
+	)
+
+	a.handleStop(m, c.Context())
+
+	return m
+}
+
+// handleStop marks the App as not ready as soon as the given manager starts stopping.
+// It stays not ready afterwards, as the process is exiting. It can be called several times with the same manager.
+func (a *App) handleStop(m *lifecycle.Manager, ctx context.Context) {
+	m.OnStop(func() {
+		if !a.stopping.Swap(true) {
+			gLog.Info(ctx).LogMsg("marked as not ready")
+		}
+	})
+}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, c.StopSignals()...)
+// run runs the given manager. If a single process or shutdown hook failed, its error is returned unchanged,
+// so that it can be compared by the callers running a single process. (ie: err == errStopped)
+func run(m *lifecycle.Manager) error {
+	err := m.Run()
 
-	select {
-	case err := <-processErrCh:
+	errs, ok := err.(lifecycle.Errors)
+	if !ok || len(errs) != 1 {
 		return err
-	case <-done:
-		break
 	}
 
-	ctx, cancel := context.WithTimeout(c.Context(), c.StopTimeout())
-	defer cancel()
+	if processErr, ok := errs[0].(*lifecycle.ProcessError); ok {
+		return processErr.Err
+	}
+
+	return err
+}
+
+// serve accepts connections on ln, or on the server address if ln is nil, until the server is shut down.
+func serve(srv *http.Server, ln net.Listener) error {
+	var err error
+	if ln != nil {
+		err = srv.Serve(ln)
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		return err
+	}
 
-	return shutdown(ctx)
+	return nil
 }
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestNewServer(t *testing.T) {
+	srv := NewServer(NewMux())
+	assert.Equal(t, 10*time.Second, srv.ReadHeaderTimeout)
+	assert.Zero(t, srv.WriteTimeout)
+	assert.NotNil(t, srv.ErrorLog)
+
+	srv = NewServer(NewMux(),
+		WithReadTimeout(time.Second),
+		WithReadHeaderTimeout(-1),
+		WithWriteTimeout(2*time.Second),
+		WithIdleTimeout(3*time.Second),
+		WithMaxHeaderBytes(4096),
+		WithH2C(true),
+	)
+	assert.Equal(t, time.Second, srv.ReadTimeout)
+	assert.Zero(t, srv.ReadHeaderTimeout)
+	assert.Equal(t, 2*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 3*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 4096, srv.MaxHeaderBytes)
+}
+
+func TestNewServerH2C(t *testing.T) {
+	r := NewMux()
+	AddHandler(r, http.MethodGet, "/proto", handler.Func(func(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return r.Proto, nil
+	}))
+
+	ts := httptest.NewServer(NewServer(r, WithH2C(true)).Handler)
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get(ts.URL + "/proto")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `"HTTP/2.0"`, string(body))
+}
+
+func TestStartProcessAndHandleStopSignals_processError(t *testing.T) {
+	errProcess := fmt.Errorf("process failed")
+
+	err := StartProcessAndHandleStopSignals(
+		func() error {
+			return errProcess
+		},
+		nil,
+		WithStopSignals(syscall.SIGUSR1),
+	)
+	assert.True(t, err == errProcess)
+}
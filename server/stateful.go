@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+
+	"github.com/mwm-io/gapi/handler"
+	gLog "github.com/mwm-io/gapi/log"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// SetStrictMode sets the strict mode of the default App. (see App.SetStrictMode)
+func SetStrictMode(strict bool) {
+	defaultApp.SetStrictMode(strict)
+}
+
+// SetStrictMode sets the strict mode of the App.
+//
+// A handler registered with AddHandler is shared between all the requests.
+// If its middlewares write request-scoped data into the handler (ie: middleware.PathParameters{Parameters: &h.params}),
+// concurrent requests will write into the same memory.
+//
+// When such a handler is registered:
+//   - in strict mode, the registration panics so the program fails fast at startup
+//   - otherwise a warning is logged, and the handler is cloned for each request.
+//     The registration panics if it can't be cloned (ie: its middlewares write into unexported fields).
+func (a *App) SetStrictMode(strict bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.strict = strict
+}
+
+func (a *App) isStrict() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.strict
+}
+
+// checkStateless detects a static handler whose middlewares write request-scoped data into shared memory.
+// In strict mode, or if the handler can't be cloned, it panics. Otherwise the handler is cloned for each request.
+func (e *defaultHandleEngine) checkStateless(method, path string) {
+	h := e.getHandler()
+
+	var stateful bool
+	for _, m := range append(handlerMiddlewares(h), e.middlewares...) {
+		if binding, ok := m.(handler.BindingMiddleware); ok && !isNilTarget(binding.BindingTarget()) {
+			stateful = true
+			break
+		}
+	}
+
+	if !stateful {
+		return
+	}
+
+	message := fmt.Sprintf("handler %T registered on %s %s writes request-scoped data into shared memory: register it with AddHandlerFactory", h, method, path)
+	if e.app.isStrict() {
+		panic("gapi: " + message)
+	}
+
+	newInstance, err := newCloningFactory(h, e.middlewares)
+	if err != nil {
+		panic(fmt.Sprintf("gapi: %s (it can't be cloned: %s)", message, err.Error()))
+	}
+
+	gLog.Warn(context.Background()).
+		With(zap.String("method", method), zap.String("path", path)).
+		LogMsg("%s: it will be cloned for each request", message)
+
+	e.static = false
+	e.newInstance = newInstance
+}
+
+// newCloningFactory returns a function returning a copy of h for each call,
+// with its binding middlewares writing into the copy.
+// It returns an error if h can't be cloned safely.
+func newCloningFactory(h handler.Handler, routeMiddlewares []handler.Middleware) (func() handler.Handler, error) {
+	for _, m := range routeMiddlewares {
+		if binding, ok := m.(handler.BindingMiddleware); ok && !isNilTarget(binding.BindingTarget()) {
+			return nil, fmt.Errorf("route middleware %T writes into shared memory", m)
+		}
+	}
+
+	original := reflect.ValueOf(h)
+	if original.Kind() != reflect.Ptr || original.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only pointers to struct can be cloned")
+	}
+
+	// paths are the indexes of the handler fields targeted by the binding middlewares, by middleware index.
+	middlewares := handlerMiddlewares(h)
+	paths := make(map[int][]int)
+	for i, m := range middlewares {
+		binding, ok := m.(handler.BindingMiddleware)
+		if !ok || isNilTarget(binding.BindingTarget()) {
+			continue
+		}
+
+		target := reflect.ValueOf(binding.BindingTarget())
+		if target.Kind() != reflect.Ptr {
+			return nil, fmt.Errorf("middleware %T doesn't write through a pointer", m)
+		}
+
+		path, ok := fieldPath(original.Elem(), target)
+		if !ok {
+			return nil, fmt.Errorf("middleware %T writes outside of the handler", m)
+		}
+
+		if !original.Elem().FieldByIndex(path).Addr().CanInterface() {
+			return nil, fmt.Errorf("middleware %T writes into an unexported field", m)
+		}
+
+		paths[i] = path
+	}
+
+	return func() handler.Handler {
+		clone := reflect.New(original.Elem().Type())
+		clone.Elem().Set(original.Elem())
+
+		instanceMiddlewares := make([]handler.Middleware, len(middlewares))
+		for i, m := range middlewares {
+			path, ok := paths[i]
+			if !ok {
+				instanceMiddlewares[i] = m
+				continue
+			}
+
+			target := clone.Elem().FieldByIndex(path).Addr()
+			instanceMiddlewares[i] = m.(handler.BindingMiddleware).WithBindingTarget(target.Interface())
+		}
+
+		return clonedHandler{
+			Handler:     clone.Interface().(handler.Handler),
+			middlewares: instanceMiddlewares,
+		}
+	}, nil
+}
+
+// fieldPath returns the index sequence of the field of the struct v pointed by target, or of its nested fields.
+// The path is empty if target points to v itself.
+func fieldPath(v reflect.Value, target reflect.Value) ([]int, bool) {
+	if target.Type().Elem() == v.Type() && target.Pointer() == v.Addr().Pointer() {
+		return []int{}, true
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if target.Type().Elem() == field.Type() && target.Pointer() == field.Addr().Pointer() {
+			return []int{i}, true
+		}
+
+		if field.Kind() != reflect.Struct {
+			continue
+		}
+
+		if path, ok := fieldPath(field, target); ok {
+			return append([]int{i}, path...), true
+		}
+	}
+
+	return nil, false
+}
+
+// clonedHandler is a copy of a handler, with its own middlewares.
+// It forwards the optional interfaces of the copy, and Unwrap returns the copy for the others.
+type clonedHandler struct {
+	handler.Handler
+	middlewares []handler.Middleware
+}
+
+// Middlewares implements the handler.MiddlewareAware interface
+func (h clonedHandler) Middlewares() []handler.Middleware {
+	return h.middlewares
+}
+
+// Doc implements the openapi.Documented interface
+func (h clonedHandler) Doc(builder *openapi.DocBuilder) error {
+	if documented, ok := h.Handler.(openapi.Documented); ok {
+		return documented.Doc(builder)
+	}
+
+	return builder.Error()
+}
+
+// Unwrap returns the copy of the original handler.
+func (h clonedHandler) Unwrap() handler.Handler {
+	return h.Handler
+}
+
+// handlerMiddlewares returns the middlewares of the given handler, if any.
+func handlerMiddlewares(h handler.Handler) []handler.Middleware {
+	if middlewareHandler, ok := h.(handler.MiddlewareAware); ok {
+		return middlewareHandler.Middlewares()
+	}
+
+	return nil
+}
+
+// isNilTarget returns true if the given binding target is nil or a nil pointer.
+func isNilTarget(target interface{}) bool {
+	if target == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(target)
+
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
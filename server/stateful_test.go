@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// clonablePathParamsHandler binds its parameters into an exported field, so it can be cloned.
+type clonablePathParamsHandler struct {
+	handler.WithMiddlewares
+
+	Params struct {
+		ID string `path:"id"`
+	}
+}
+
+func newClonablePathParamsHandler() *clonablePathParamsHandler {
+	h := &clonablePathParamsHandler{}
+	h.MiddlewareList = []handler.Middleware{
+		middleware.PathParameters{Parameters: &h.Params},
+	}
+
+	return h
+}
+
+func (h *clonablePathParamsHandler) Serve(http.ResponseWriter, *http.Request) (interface{}, error) {
+	return h.Params.ID, nil
+}
+
+func (h *clonablePathParamsHandler) Doc(builder *openapi.DocBuilder) error {
+	builder.WithSummary("Get by id")
+	return builder.Error()
+}
+
+func TestAddHandler_statefulHandlerIsCloned(t *testing.T) {
+	app := NewApp()
+	app.AddHandler(http.MethodGet, "/{id}", newClonablePathParamsHandler())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%d", i), nil))
+			assert.Equal(t, fmt.Sprintf(`"%d"`, i), w.Body.String())
+		}(i)
+	}
+
+	wg.Wait()
+
+	// The parameters of pathParamsHandler are unexported: it can't be cloned.
+	assert.Panics(t, func() {
+		app.AddHandler(http.MethodGet, "/other/{id}", newPathParamsHandler())
+	})
+}
+
+func TestAddHandler_strictMode(t *testing.T) {
+	app := NewApp()
+	app.SetStrictMode(true)
+
+	assert.Panics(t, func() {
+		app.AddHandler(http.MethodGet, "/{id}", newPathParamsHandler())
+	})
+
+	assert.NotPanics(t, func() {
+		app.AddHandlerFactory(http.MethodGet, "/{id}", newPathParamsHandler)
+		app.AddHandler(http.MethodGet, "/", helloHandler)
+	})
+}
+
+func TestNewCloningFactory_keepsDoc(t *testing.T) {
+	newInstance, err := newCloningFactory(newClonablePathParamsHandler(), nil)
+	require.NoError(t, err)
+
+	clone := newInstance()
+	documented, ok := clone.(openapi.Documented)
+	require.True(t, ok)
+
+	builder := openapi.NewDocBuilder(&openapi3.Reflector{}, http.MethodGet, "/{id}")
+	require.NoError(t, documented.Doc(builder))
+	assert.Equal(t, "Get by id", *builder.Operation().Summary)
+	assert.IsType(t, &clonablePathParamsHandler{}, clone.(clonedHandler).Unwrap())
+}
@@ -0,0 +1,535 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+	"github.com/mwm-io/gapi/response"
+)
+
+// DefaultIndexFile is the default file served for a directory. (see WithIndexFile)
+const DefaultIndexFile = "index.html"
+
+// StaticOption is an option to modify the default configuration of a static handler. (see AddStaticHandler)
+type StaticOption func(*staticOptions)
+
+type staticOptions struct {
+	indexFile     string
+	spaFallback   bool
+	precompressed bool
+	cacheControl  []cacheControlRule
+	middlewares   []handler.Middleware
+}
+
+type cacheControlRule struct {
+	pattern string
+	value   string
+}
+
+// WithIndexFile sets the file served for a directory. Default to DefaultIndexFile.
+func WithIndexFile(name string) StaticOption {
+	return func(o *staticOptions) {
+		o.indexFile = name
+	}
+}
+
+// WithSPAFallback serves the index file of the root directory for the paths without extension that don't match any file,
+// so that a single page application can handle its own routes.
+func WithSPAFallback() StaticOption {
+	return func(o *staticOptions) {
+		o.spaFallback = true
+	}
+}
+
+// WithPrecompressed serves the precompressed sibling of a file (ie: app.js.gz for app.js) when it exists
+// and the client accepts the gzip encoding.
+func WithPrecompressed() StaticOption {
+	return func(o *staticOptions) {
+		o.precompressed = true
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of the files matching the given glob pattern. (see path.Match)
+// A pattern without "/" is matched against the file base name, otherwise against its path from the root of the fs.FS.
+// The first matching pattern is used:
+//
+//	server.WithCacheControl("index.html", "no-cache")
+//	server.WithCacheControl("assets/*", "public, max-age=31536000, immutable")
+func WithCacheControl(pattern, value string) StaticOption {
+	return func(o *staticOptions) {
+		o.cacheControl = append(o.cacheControl, cacheControlRule{pattern: pattern, value: value})
+	}
+}
+
+// WithStaticMiddlewares adds middlewares to the static handler, in addition to the default middlewares.
+func WithStaticMiddlewares(middlewares ...handler.Middleware) StaticOption {
+	return func(o *staticOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// AddStaticHandler registers a handler serving the files of fsys under the given path prefix, for GET and HEAD requests.
+// The handler runs the middleware chain like any other handler, and supports:
+//
+//   - strong ETags, computed once per file, and the If-None-Match, If-Modified-Since and If-Range headers
+//   - single byte Range requests
+//   - precompressed .gz siblings (see WithPrecompressed)
+//   - a fallback to the index file for single page applications (see WithSPAFallback)
+//   - Cache-Control headers by glob pattern (see WithCacheControl)
+//
+// It works with any fs.FS, like an embed.FS or os.DirFS:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	assets, _ := fs.Sub(dist, "dist")
+//	server.AddStaticHandler(r, "/", assets, server.WithSPAFallback())
+//
+// The handler matches all the paths under the prefix: register it after the other handlers.
+func AddStaticHandler(r *mux.Router, prefix string, fsys fs.FS, opts ...StaticOption) {
+	defaultApp.addStaticHandler(r, prefix, fsys, opts...)
+}
+
+// AddStaticHandler registers a handler serving the files of fsys under the given path prefix. (see AddStaticHandler)
+func (a *App) AddStaticHandler(prefix string, fsys fs.FS, opts ...StaticOption) {
+	a.addStaticHandler(a.router, prefix, fsys, opts...)
+}
+
+func (a *App) addStaticHandler(r *mux.Router, prefix string, fsys fs.FS, opts ...StaticOption) {
+	h := newStaticHandler(fsys, opts...)
+	route := strings.TrimSuffix(prefix, "/") + "/{path:.*}"
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		a.addRoute(r, method, route, &defaultHandleEngine{
+			getHandler: staticFactory(h),
+			static:     true,
+		})
+	}
+}
+
+// staticHandler serves the files of a fs.FS.
+type staticHandler struct {
+	handler.WithMiddlewares
+
+	fsys    fs.FS
+	options staticOptions
+	// files caches the *staticFile by name.
+	files sync.Map
+}
+
+// staticFile is a file of the fs.FS, with its ETag and content type computed once.
+type staticFile struct {
+	name        string
+	size        int64
+	modTime     time.Time
+	etag        string
+	contentType string
+
+	gzipOnce sync.Once
+	gzip     *staticFile
+}
+
+// staticContent is the response of a staticHandler: the content of a file, or a part of it, with its status code.
+type staticContent struct {
+	reader     io.Reader
+	closer     io.Closer
+	statusCode int
+}
+
+func newStaticHandler(fsys fs.FS, opts ...StaticOption) *staticHandler {
+	options := staticOptions{
+		indexFile: DefaultIndexFile,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &staticHandler{
+		WithMiddlewares: handler.WithMiddlewares{MiddlewareList: options.middlewares},
+		fsys:            fsys,
+		options:         options,
+	}
+}
+
+// Serve implements the handler.Handler interface
+func (h *staticHandler) Serve(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	urlPath := mux.Vars(r)["path"]
+	if h.isDirWithoutSlash(urlPath) {
+		// Like http.FileServer, redirect to the directory path, so that the relative links of its index file work.
+		target := path.Base(r.URL.Path) + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+
+		return response.Redirect{URL: target, StatusCode: http.StatusMovedPermanently}, nil
+	}
+
+	file, err := h.resolve(urlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheControl := h.cacheControl(file.name); cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	if h.options.precompressed {
+		if gzipFile := h.gzipSibling(file); gzipFile != nil {
+			middleware.AddVary(w.Header(), "Accept-Encoding")
+
+			if middleware.NegotiateEncoding(r.Header.Get("Accept-Encoding"), middleware.EncodingGzip) != "" {
+				w.Header().Set("Content-Encoding", "gzip")
+				file = gzipFile
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", file.contentType)
+	w.Header().Set("ETag", file.etag)
+	w.Header().Set("Accept-Ranges", "bytes")
+	if !file.modTime.IsZero() {
+		w.Header().Set("Last-Modified", file.modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if middleware.NotModified(r, file.etag, file.modTime) {
+		w.Header().Del("Content-Type")
+		return staticContent{reader: http.NoBody, statusCode: http.StatusNotModified}, nil
+	}
+
+	start, length, partial, err := contentRange(r, file)
+	if err != nil {
+		w.Header().Del("Content-Encoding")
+		w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(file.size, 10))
+		return nil, err
+	}
+
+	statusCode := http.StatusOK
+	if partial {
+		statusCode = http.StatusPartialContent
+		w.Header().Set("Content-Range", "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(start+length-1, 10)+"/"+strconv.FormatInt(file.size, 10))
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+
+	if r.Method == http.MethodHead {
+		return staticContent{reader: http.NoBody, statusCode: statusCode}, nil
+	}
+
+	f, err := h.fsys.Open(file.name)
+	if err != nil {
+		return nil, errors.Wrap(err).WithKind("static_file_open_failed")
+	}
+
+	if start > 0 {
+		if seeker, ok := f.(io.Seeker); ok {
+			_, err = seeker.Seek(start, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, f, start)
+		}
+
+		if err != nil {
+			_ = f.Close()
+			return nil, errors.Wrap(err).WithKind("static_file_read_failed")
+		}
+	}
+
+	return staticContent{
+		reader:     io.LimitReader(f, length),
+		closer:     f,
+		statusCode: statusCode,
+	}, nil
+}
+
+// Doc implements the openapi.Documented interface
+func (h *staticHandler) Doc(builder *openapi.DocBuilder) error {
+	builder.
+		WithSummary("Static files").
+		WithDescription("Serve the static files, with support of conditional and range requests.").
+		WithParams(struct {
+			Path        string `path:"path" description:"Path of the file."`
+			IfNoneMatch string `header:"If-None-Match"`
+			Range       string `header:"Range" example:"bytes=0-1023"`
+		}{}).
+		WithResponse("", openapi.WithMimeType("application/octet-stream"), openapi.WithDescription("The file content.")).
+		WithResponse("", openapi.WithStatusCode(http.StatusPartialContent), openapi.WithMimeType("application/octet-stream"), openapi.WithDescription("The requested range of the file content.")).
+		WithResponse(nil, openapi.WithStatusCode(http.StatusNotModified), openapi.WithDescription("The file has not been modified.")).
+		WithResponse(nil, openapi.WithStatusCode(http.StatusMovedPermanently), openapi.WithDescription("The path is a directory: redirect to the path with a trailing slash.")).
+		WithError(http.StatusNotFound, "file_not_found", "file not found").
+		WithError(http.StatusRequestedRangeNotSatisfiable, "range_not_satisfiable", "the requested range is not satisfiable")
+
+	return builder.Error()
+}
+
+// isDirWithoutSlash returns true if the given path is a directory without trailing slash.
+func (h *staticHandler) isDirWithoutSlash(urlPath string) bool {
+	if urlPath == "" || strings.HasSuffix(urlPath, "/") {
+		return false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return false
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+
+	return err == nil && info.IsDir()
+}
+
+// resolve returns the file to serve for the given path: the file itself, the index file of a directory,
+// or the root index file if the SPA fallback is enabled.
+func (h *staticHandler) resolve(urlPath string) (*staticFile, error) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	file, err := h.file(name)
+	if err == nil {
+		return file, nil
+	}
+
+	if h.options.spaFallback && path.Ext(name) == "" {
+		if file, err := h.file("."); err == nil {
+			return file, nil
+		}
+	}
+
+	return nil, errors.NotFound("file_not_found", "file %s not found", urlPath)
+}
+
+// file returns the *staticFile of the given name, or of its index file if it is a directory.
+// The ETag and content type of a file are computed again only if its size or modification time changed.
+func (h *staticHandler) file(name string) (*staticFile, error) {
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		name = path.Join(name, h.options.indexFile)
+		if info, err = fs.Stat(h.fsys, name); err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			return nil, fs.ErrNotExist
+		}
+	}
+
+	if cached, ok := h.files.Load(name); ok {
+		file := cached.(*staticFile)
+		if file.size == info.Size() && file.modTime.Equal(info.ModTime()) {
+			return file, nil
+		}
+	}
+
+	file, err := h.newStaticFile(name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	h.files.Store(name, file)
+
+	return file, nil
+}
+
+// newStaticFile reads the given file to compute its ETag and its content type.
+func (h *staticHandler) newStaticFile(name string, info fs.FileInfo) (*staticFile, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	var head bytes.Buffer
+
+	size, err := io.Copy(io.MultiWriter(hash, &limitedBuffer{buf: &head, limit: 512}), f)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(head.Bytes())
+	}
+
+	return &staticFile{
+		name:        name,
+		size:        size,
+		modTime:     info.ModTime(),
+		etag:        `"` + base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:18]) + `"`,
+		contentType: contentType,
+	}, nil
+}
+
+// gzipSibling returns the precompressed sibling of the given file, or nil if it doesn't exist.
+func (h *staticHandler) gzipSibling(file *staticFile) *staticFile {
+	file.gzipOnce.Do(func() {
+		info, err := fs.Stat(h.fsys, file.name+".gz")
+		if err != nil || info.IsDir() {
+			return
+		}
+
+		gzipFile, err := h.newStaticFile(file.name+".gz", info)
+		if err != nil {
+			return
+		}
+
+		// The precompressed sibling has the content type of the original file.
+		gzipFile.contentType = file.contentType
+		file.gzip = gzipFile
+	})
+
+	return file.gzip
+}
+
+// cacheControl returns the Cache-Control header of the file, from the first matching rule.
+func (h *staticHandler) cacheControl(name string) string {
+	for _, rule := range h.options.cacheControl {
+		target := name
+		if !strings.Contains(rule.pattern, "/") {
+			target = path.Base(name)
+		}
+
+		if matched, _ := path.Match(rule.pattern, target); matched {
+			return rule.value
+		}
+	}
+
+	return ""
+}
+
+// contentRange returns the part of the file to serve, from the Range and If-Range headers.
+// Only single byte ranges are supported: the whole file is served for multiple ranges.
+func contentRange(r *http.Request, file *staticFile) (start, length int64, partial bool, err error) {
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader == "" || !ifRangeMatches(r, file) {
+		return 0, file.size, false, nil
+	}
+
+	if !strings.HasPrefix(rangeHeader, "bytes=") {
+		return 0, file.size, false, nil
+	}
+
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	if strings.Contains(spec, ",") {
+		return 0, file.size, false, nil
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, file.size, false, nil
+	}
+
+	notSatisfiable := errors.RequestedRangeNotSatisfiable("range_not_satisfiable", "range %s is not satisfiable for a size of %d bytes", spec, file.size)
+
+	if first == "" {
+		// Suffix range: the last n bytes.
+		n, errParse := strconv.ParseInt(last, 10, 64)
+		if errParse != nil || n <= 0 {
+			return 0, 0, false, notSatisfiable
+		}
+
+		if n > file.size {
+			n = file.size
+		}
+
+		return file.size - n, n, true, nil
+	}
+
+	start, errParse := strconv.ParseInt(first, 10, 64)
+	if errParse != nil || start < 0 || start >= file.size {
+		return 0, 0, false, notSatisfiable
+	}
+
+	end := file.size - 1
+	if last != "" {
+		end, errParse = strconv.ParseInt(last, 10, 64)
+		if errParse != nil || end < start {
+			return 0, 0, false, notSatisfiable
+		}
+
+		if end >= file.size {
+			end = file.size - 1
+		}
+	}
+
+	return start, end - start + 1, true, nil
+}
+
+// ifRangeMatches returns true if the Range header must be used: there is no If-Range header,
+// or it matches the current version of the file.
+func ifRangeMatches(r *http.Request, file *staticFile) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return middleware.MatchETag(ifRange, file.etag, true)
+	}
+
+	since, err := http.ParseTime(ifRange)
+	if err != nil || file.modTime.IsZero() {
+		return false
+	}
+
+	return file.modTime.Truncate(time.Second).Equal(since)
+}
+
+// StatusCode implements the middleware.WithStatusCode interface
+func (c staticContent) StatusCode() int {
+	return c.statusCode
+}
+
+// Read implements the io.Reader interface
+func (c staticContent) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// Close implements the io.Closer interface
+func (c staticContent) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+
+	return c.closer.Close()
+}
+
+// limitedBuffer is a io.Writer keeping the first bytes written into it.
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+// Write implements the io.Writer interface
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+
+		b.buf.Write(p[:remaining])
+	}
+
+	return len(p), nil
+}
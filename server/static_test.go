@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStaticHandler(t *testing.T) {
+	modTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":        {Data: []byte("<html>app</html>"), ModTime: modTime},
+		"assets/app.js":     {Data: []byte("console.log('hello')"), ModTime: modTime},
+		"assets/app.js.gz":  {Data: []byte("gzipped"), ModTime: modTime},
+		"assets/styles.css": {Data: []byte("body{}"), ModTime: modTime},
+	}
+
+	app := NewApp()
+	app.UseMiddlewares(headerMiddleware{value: "static"})
+	app.AddStaticHandler("/", fsys,
+		WithSPAFallback(),
+		WithPrecompressed(),
+		WithCacheControl("index.html", "no-cache"),
+		WithCacheControl("assets/*", "public, max-age=31536000, immutable"),
+	)
+
+	serve := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		for name, value := range header {
+			r.Header.Set(name, value)
+		}
+
+		w := httptest.NewRecorder()
+		app.Router().ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := serve(http.MethodGet, "/assets/styles.css", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body{}", w.Body.String())
+	assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Mon, 02 Jan 2023 03:04:05 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "static", w.Header().Get("X-Middleware"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = serve(http.MethodGet, "/assets/styles.css", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = serve(http.MethodGet, "/assets/styles.css", map[string]string{"If-Modified-Since": "Mon, 02 Jan 2023 03:04:05 GMT"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve(http.MethodGet, "/assets/styles.css", map[string]string{"Range": "bytes=1-3"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "ody", w.Body.String())
+	assert.Equal(t, "bytes 1-3/6", w.Header().Get("Content-Range"))
+
+	w = serve(http.MethodGet, "/assets/styles.css", map[string]string{"Range": "bytes=-2", "If-Range": `"outdated"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body{}", w.Body.String())
+
+	w = serve(http.MethodGet, "/assets/styles.css", map[string]string{"Range": "bytes=10-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */6", w.Header().Get("Content-Range"))
+
+	w = serve(http.MethodGet, "/assets/app.js", map[string]string{"Accept-Encoding": "br, gzip"})
+	assert.Equal(t, "gzipped", w.Body.String())
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+
+	w = serve(http.MethodGet, "/assets/app.js", map[string]string{"Accept-Encoding": "gzip;q=0"})
+	assert.Equal(t, "console.log('hello')", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	w = serve(http.MethodGet, "/users/42", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<html>app</html>", w.Body.String())
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	w = serve(http.MethodHead, "/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "16", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	w = serve(http.MethodGet, "/assets/missing.js", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"kind":"file_not_found","message":"file assets/missing.js not found"}`, w.Body.String())
+
+	w = serve(http.MethodGet, "/assets?v=1", nil)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/assets/?v=1", w.Header().Get("Location"))
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	gLog "github.com/mwm-io/gapi/log"
+)
+
+type contextKey string
+
+// peerIdentityKey is the key for PeerIdentity values in Contexts.
+var peerIdentityKey contextKey = "gapi-peer-identity"
+
+// PeerIdentity is the identity of a client authenticated with a verified TLS certificate (mTLS).
+type PeerIdentity struct {
+	// Certificate is the verified client certificate.
+	Certificate *x509.Certificate
+	// Chain is the verified chain, from the client certificate to the trusted CA.
+	Chain []*x509.Certificate
+}
+
+// CommonName returns the subject common name of the client certificate.
+func (p PeerIdentity) CommonName() string {
+	return p.Certificate.Subject.CommonName
+}
+
+// DNSNames returns the DNS subject alternative names of the client certificate.
+func (p PeerIdentity) DNSNames() []string {
+	return p.Certificate.DNSNames
+}
+
+// URIs returns the URI subject alternative names of the client certificate. (ie: SPIFFE IDs)
+func (p PeerIdentity) URIs() []*url.URL {
+	return p.Certificate.URIs
+}
+
+// PeerIdentityFromContext returns the PeerIdentity of the client stored in Context.
+// It is only present if the client presented a certificate verified against the client CA. (see WithClientCA)
+func PeerIdentityFromContext(ctx context.Context) (PeerIdentity, bool) {
+	if ctx == nil {
+		return PeerIdentity{}, false
+	}
+
+	p, ok := ctx.Value(peerIdentityKey).(PeerIdentity)
+
+	return p, ok
+}
+
+// withPeerIdentity stores the verified client certificate of TLS requests in the request context.
+func withPeerIdentity(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) != 0 && len(r.TLS.VerifiedChains[0]) != 0 {
+			chain := r.TLS.VerifiedChains[0]
+			r = r.WithContext(context.WithValue(r.Context(), peerIdentityKey, PeerIdentity{
+				Certificate: chain[0],
+				Chain:       chain,
+			}))
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// newTLSConfig returns the TLS configuration of the server, serving the certificate loaded from the given files.
+// The returned certReloader is nil if no file is given: the certificates must then be set in the WithTLSConfig configuration.
+func newTLSConfig(c serverOptions, certFile, keyFile string) (*tls.Config, *certReloader, error) {
+	tlsConfig := c.TLSConfig()
+
+	var reloader *certReloader
+	if certFile != "" || keyFile != "" {
+		var err error
+		if reloader, err = newCertReloader(certFile, keyFile); err != nil {
+			return nil, nil, err
+		}
+
+		tlsConfig.Certificates = nil
+		tlsConfig.GetCertificate = reloader.GetCertificate
+	}
+
+	if c.ClientCAFile() != "" {
+		pem, err := os.ReadFile(c.ClientCAFile())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificate found in client CA bundle %s", c.ClientCAFile())
+		}
+
+		tlsConfig.ClientCAs = pool
+		if tlsConfig.ClientAuth == tls.NoClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return tlsConfig, reloader, nil
+}
+
+// certReloader serves a certificate loaded from files, and reloads it when the files change.
+// Connections already established keep their certificate, new connections use the reloaded one.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	cert     atomic.Pointer[tls.Certificate]
+	certPEM  []byte
+	keyPEM   []byte
+	modTimes [2]time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// GetCertificate implements the tls.Config GetCertificate function.
+func (l *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return l.cert.Load(), nil
+}
+
+// reload loads the certificate from the files.
+// It returns false if the files content didn't change.
+func (l *certReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(l.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read certificate: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(l.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read certificate key: %w", err)
+	}
+
+	if bytes.Equal(certPEM, l.certPEM) && bytes.Equal(keyPEM, l.keyPEM) {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	l.cert.Store(&cert)
+	l.certPEM, l.keyPEM = certPEM, keyPEM
+
+	return true, nil
+}
+
+// changed returns true if the modification time of one of the files changed since the last call.
+func (l *certReloader) changed() bool {
+	var modTimes [2]time.Time
+	for i, file := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	changed := modTimes != l.modTimes
+	l.modTimes = modTimes
+
+	return changed
+}
+
+// watch reloads the certificate when the files change, or when the process receives a SIGHUP, until ctx is done.
+// If the new certificate can't be loaded, the error is logged and the current certificate is kept.
+func (l *certReloader) watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+		l.changed()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			if !l.changed() {
+				continue
+			}
+		}
+
+		reloaded, err := l.reload()
+		if err != nil {
+			gLog.Error(ctx).LogError(err)
+			continue
+		}
+
+		if reloaded {
+			gLog.Info(ctx).LogMsg("TLS certificate %s reloaded", l.certFile)
+		}
+	}
+}
+
+// newRedirectServer returns a *http.Server listening on the given port,
+// redirecting all the requests to the same URL using https, on the port of the TLS server.
+func newRedirectServer(c serverOptions, port string) *http.Server {
+	_, tlsPort, _ := net.SplitHostPort(c.AddrHttps())
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		ReadHeaderTimeout: c.ReadHeaderTimeout(),
+		ErrorLog:          newErrorLog(c.Context()),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+
+			if tlsPort != "" && tlsPort != "443" {
+				host = net.JoinHostPort(host, tlsPort)
+			}
+
+			target := url.URL{
+				Scheme:   "https",
+				Host:     host,
+				Path:     r.URL.Path,
+				RawPath:  r.URL.RawPath,
+				RawQuery: r.URL.RawQuery,
+			}
+
+			http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+		}),
+		BaseContext: func(listener net.Listener) context.Context {
+			return c.Context()
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/handler"
+)
+
+func TestServeMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	writeTestCertificate(t, filepath.Join(dir, "ca"), ca, caKey)
+
+	serverCert, serverKey := newTestCertificate(t, "localhost", ca, caKey)
+	writeTestCertificate(t, filepath.Join(dir, "server"), serverCert, serverKey)
+
+	clientCert, clientKey := newTestCertificate(t, "client", ca, caKey)
+
+	r := NewMux()
+	AddHandler(r, http.MethodGet, "/whoami", handler.Func(func(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+		peer, ok := PeerIdentityFromContext(r.Context())
+		if !ok {
+			return "anonymous", nil
+		}
+
+		return peer.CommonName(), nil
+	}))
+
+	opts := []Option{WithClientCA(filepath.Join(dir, "ca.crt"))}
+	tlsConfig, reloader, err := newTLSConfig(newOptions(opts...), filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := NewServer(r, opts...)
+	srv.TLSConfig = tlsConfig
+	go func() {
+		_ = srv.ServeTLS(ln, "", "")
+	}()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	get := func(serverName string, certificates ...tls.Certificate) (*http.Response, string, error) {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      roots,
+					ServerName:   serverName,
+					Certificates: certificates,
+				},
+			},
+		}
+
+		resp, err := client.Get("https://" + ln.Addr().String() + "/whoami")
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		return resp, string(body), err
+	}
+
+	clientCertificate := tls.Certificate{
+		Certificate: [][]byte{clientCert.Raw},
+		PrivateKey:  clientKey,
+	}
+
+	resp, body, err := get("localhost", clientCertificate)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"client"`, body)
+	assert.Equal(t, "localhost", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	renewedCert, renewedKey := newTestCertificate(t, "renewed.localhost", ca, caKey)
+	writeTestCertificate(t, filepath.Join(dir, "server"), renewedCert, renewedKey)
+
+	reloaded, err := reloader.reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	resp, _, err = get("renewed.localhost", clientCertificate)
+	require.NoError(t, err)
+	assert.Equal(t, "renewed.localhost", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	_, _, err = get("renewed.localhost")
+	assert.Error(t, err)
+}
+
+func TestRedirectServer(t *testing.T) {
+	srv := newRedirectServer(newOptions(WithPort("8443")), "8080")
+
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://example.com:8080/users?page=2", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "https://example.com:8443/users?page=2", w.Header().Get("Location"))
+}
+
+// newTestCertificate returns a new certificate signed by the given parent, or a self-signed CA if parent is nil.
+func newTestCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+// writeTestCertificate writes the certificate and its key in PEM files named path.crt and path.key.
+func writeTestCertificate(t *testing.T, path string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path+".crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(path+".key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
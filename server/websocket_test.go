@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwm-io/gapi/errors"
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/websocket"
+)
+
+type echoMessage struct {
+	Text string `json:"text"`
+}
+
+var requireToken = handlerMiddlewareFunc(func(h handler.Handler) handler.Handler {
+	return handler.Func(func(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if r.URL.Query().Get("token") != "secret" {
+			return nil, errors.Unauthorized("missing_token", "missing token")
+		}
+
+		return h.Serve(w, r)
+	})
+})
+
+type handlerMiddlewareFunc func(handler.Handler) handler.Handler
+
+func (f handlerMiddlewareFunc) Wrap(h handler.Handler) handler.Handler {
+	return f(h)
+}
+
+func TestWebSocket(t *testing.T) {
+	app := NewApp()
+	app.UseMiddlewares(headerMiddleware{value: "ws"})
+	app.AddHandler(http.MethodGet, "/echo", handler.NewWebSocket(func(ctx context.Context, conn *websocket.Conn) error {
+		for {
+			msg, err := websocket.Receive[echoMessage](conn)
+			if err != nil {
+				return err
+			}
+
+			if msg.Text == "" {
+				return errors.BadRequest("empty_message", "message can't be empty")
+			}
+
+			if err := conn.Send(msg); err != nil {
+				return err
+			}
+		}
+	}, requireToken))
+
+	srv := httptest.NewServer(app.Router())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/echo?token=secret")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+
+	conn, br, resp := dialWebSocket(t, srv.Listener.Addr().String(), "/echo")
+	_ = conn.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, br, resp = dialWebSocket(t, srv.Listener.Addr().String(), "/echo?token=secret")
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "ws", resp.Header.Get("X-Middleware"))
+
+	writeClientFrame(t, conn, 0x9, []byte("ping"))
+	opcode, payload := readServerFrame(t, br)
+	assert.Equal(t, byte(0xA), opcode)
+	assert.Equal(t, "ping", string(payload))
+
+	writeClientFrame(t, conn, 0x1, []byte(`{"text":"hello"}`))
+	opcode, payload = readServerFrame(t, br)
+	assert.Equal(t, byte(0x1), opcode)
+	assert.JSONEq(t, `{"text":"hello"}`, string(payload))
+
+	writeClientFrame(t, conn, 0x1, []byte(`{"text":""}`))
+	opcode, payload = readServerFrame(t, br)
+	assert.Equal(t, byte(0x8), opcode)
+	require.True(t, len(payload) >= 2)
+	assert.Equal(t, 4400, int(binary.BigEndian.Uint16(payload)))
+	assert.Equal(t, "message can't be empty", string(payload[2:]))
+}
+
+func TestWebSocket_timeoutAndCodec(t *testing.T) {
+	app := NewApp()
+	app.AddHandler(http.MethodGet, "/echo", handler.NewWebSocket(func(ctx context.Context, conn *websocket.Conn) error {
+		msg, err := websocket.Receive[echoMessage](conn)
+		if err != nil {
+			return err
+		}
+
+		return conn.Send(msg)
+	},
+		// The messages use the codec of the response writer.
+		middleware.MakeResponseWriter().SetForcedContentType("application/xml"),
+		middleware.MakeTimeout(20*time.Millisecond),
+	))
+
+	srv := httptest.NewServer(app.Router())
+	defer srv.Close()
+
+	conn, br, resp := dialWebSocket(t, srv.Listener.Addr().String(), "/echo")
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// The connection outlives the request timeout.
+	time.Sleep(60 * time.Millisecond)
+
+	writeClientFrame(t, conn, 0x1, []byte(`<echoMessage><Text>hello</Text></echoMessage>`))
+	opcode, payload := readServerFrame(t, br)
+	assert.Equal(t, byte(0x1), opcode)
+	assert.Equal(t, `<echoMessage><Text>hello</Text></echoMessage>`, string(payload))
+}
+
+// dialWebSocket sends a websocket handshake and returns the connection and the handshake response.
+func dialWebSocket(t *testing.T, addr, path string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	require.NoError(t, req.Write(conn))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+
+	return conn, br, resp
+}
+
+// writeClientFrame writes a masked frame, as a client must do.
+func writeClientFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := conn.Write(frame)
+	require.NoError(t, err)
+}
+
+// readServerFrame reads an unmasked frame smaller than 126 bytes.
+func readServerFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var header [2]byte
+	_, err := io.ReadFull(br, header[:])
+	require.NoError(t, err)
+
+	payload := make([]byte, header[1]&0x7f)
+	_, err = io.ReadFull(br, payload)
+	require.NoError(t, err)
+
+	return header[0] & 0x0f, payload
+}
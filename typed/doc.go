@@ -0,0 +1,28 @@
+/*
+Package typed provides generic handlers binding the whole request into a single struct
+and returning a concrete response type.
+
+The request struct fields are bound according to their tags:
+  - `path` for path parameters
+  - `query` for query parameters
+  - `header` for request headers
+  - `cookie` for request cookies
+  - `json` or `formData` for the request body
+
+Example:
+
+	type GetUserRequest struct {
+		ID       int    `path:"id"`
+		Full     bool   `query:"full"`
+		TenantID string `header:"X-Tenant-ID" required:"true"`
+	}
+
+	func GetUser(ctx context.Context, req GetUserRequest) (User, error) {
+		return repository.Get(ctx, req.TenantID, req.ID)
+	}
+
+	server.AddHandler(r, http.MethodGet, "/users/{id}", typed.New(GetUser))
+
+The request parameters and the response are documented automatically from the type parameters.
+*/
+package typed
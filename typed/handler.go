@@ -0,0 +1,111 @@
+package typed
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+// Func is a handler function receiving the bound request and returning a concrete response.
+type Func[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Handler is a handler.Handler that will bind the incoming request into a new Req
+// for every call (see middleware.Request) and call its Func with it.
+//
+// Because the request is bound into a new value for every call, a Handler can safely
+// be registered with server.AddHandler.
+type Handler[Req, Resp any] struct {
+	handler.WithMiddlewares
+
+	f       Func[Req, Resp]
+	docFunc func(builder *openapi.DocBuilder)
+}
+
+// New returns a new Handler calling f with the bound request.
+// The given middlewares are added to the handler middlewares.
+func New[Req, Resp any](f Func[Req, Resp], middlewares ...handler.Middleware) Handler[Req, Resp] {
+	return Handler[Req, Resp]{
+		f: f,
+		WithMiddlewares: handler.WithMiddlewares{
+			MiddlewareList: middlewares,
+		},
+	}
+}
+
+// WithDoc set a function to add additional information to the generated documentation
+// (summary, description, tags, errors...) and return current instance.
+func (h Handler[Req, Resp]) WithDoc(f func(builder *openapi.DocBuilder)) Handler[Req, Resp] {
+	h.docFunc = f
+	return h
+}
+
+// Serve implements the handler.Handler interface
+func (h Handler[Req, Resp]) Serve(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	var req Req
+
+	defer middleware.RemoveMultipartForm(r)
+
+	if err := (middleware.Request{Parameters: &req}).Bind(r); err != nil {
+		return nil, err
+	}
+
+	resp, err := h.f(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isNil(resp) {
+		return nil, nil
+	}
+
+	return resp, nil
+}
+
+// Doc implements the openapi.Documented interface
+func (h Handler[Req, Resp]) Doc(builder *openapi.DocBuilder) error {
+	if err := (middleware.Request{Parameters: new(Req)}).Doc(builder); err != nil {
+		return err
+	}
+
+	builder.WithResponse(responseSample[Resp]())
+
+	if h.docFunc != nil {
+		h.docFunc(builder)
+	}
+
+	return builder.Error()
+}
+
+// responseSample returns a non nil value of type Resp to document the response,
+// or nil if Resp is an interface, meaning there is no content.
+func responseSample[Resp any]() interface{} {
+	t := reflect.TypeOf((*Resp)(nil)).Elem()
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil
+	case reflect.Ptr:
+		return reflect.New(t.Elem()).Interface()
+	default:
+		var resp Resp
+		return resp
+	}
+}
+
+// isNil returns true if v is nil or a nil pointer.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package typed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/mwm-io/gapi/handler"
+	"github.com/mwm-io/gapi/middleware"
+	"github.com/mwm-io/gapi/openapi"
+)
+
+type updateUserRequest struct {
+	ID       int    `path:"id" json:"-"`
+	DryRun   bool   `query:"dry_run" json:"-"`
+	TenantID string `header:"X-Tenant-ID" json:"-" required:"true"`
+	Name     string `json:"name" required:"true"`
+}
+
+type user struct {
+	ID       int    `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+func updateUser(_ context.Context, req updateUserRequest) (user, error) {
+	return user{
+		ID:       req.ID,
+		TenantID: req.TenantID,
+		Name:     req.Name,
+		DryRun:   req.DryRun,
+	}, nil
+}
+
+func serve(h handler.Handler, r *http.Request) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.Methods(http.MethodPut).Path("/users/{id}").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = middleware.MakeJSONResponseWriter().Wrap(h).Serve(w, r)
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestHandler_Serve(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/users/42?dry_run=true", strings.NewReader(`{"name":"gopher"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Tenant-ID", "mwm")
+
+	w := serve(New(updateUser), r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got user
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, user{ID: 42, TenantID: "mwm", Name: "gopher", DryRun: true}, got)
+}
+
+type spoofableRequest struct {
+	TenantID string `header:"X-Tenant-ID"`
+	Name     string `json:"name"`
+	Nickname string
+}
+
+func TestHandler_Serve_bodyDoesNotSetParameters(t *testing.T) {
+	var got spoofableRequest
+	h := New(func(_ context.Context, req spoofableRequest) (interface{}, error) {
+		got = req
+		return nil, nil
+	})
+
+	r := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(`{"name":"gopher","TenantID":"evil","Nickname":"go"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	w := serve(h, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, spoofableRequest{Name: "gopher", Nickname: "go"}, got)
+}
+
+func TestHandler_Serve_validation(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(`{"name":"gopher"}`))
+	w := serve(New(updateUser), r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "missing_param")
+
+	r = httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(`{"name":"gopher"}`))
+	r.Header.Set("X-Tenant-ID", "")
+	w = serve(New(updateUser), r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "header X-Tenant-ID is required")
+
+	r = httptest.NewRequest(http.MethodPut, "/users/abc", strings.NewReader(`{"name":"gopher"}`))
+	r.Header.Set("X-Tenant-ID", "mwm")
+	w = serve(New(updateUser), r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_param_type")
+}
+
+func TestHandler_Serve_nilResponse(t *testing.T) {
+	h := New(func(_ context.Context, _ updateUserRequest) (*user, error) {
+		return nil, nil
+	})
+
+	r := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(`{"name":"gopher"}`))
+	r.Header.Set("X-Tenant-ID", "mwm")
+	w := serve(h, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestHandler_Doc(t *testing.T) {
+	reflector := new(openapi3.Reflector)
+	builder := openapi.NewDocBuilder(reflector, http.MethodPut, "/users/{id}")
+
+	assert.NoError(t, New(updateUser).Doc(builder))
+	assert.NoError(t, builder.Commit().Error())
+
+	operation := builder.Operation()
+
+	params := map[string]openapi3.ParameterIn{}
+	for _, param := range operation.Parameters {
+		params[param.Parameter.Name] = param.Parameter.In
+	}
+	assert.Equal(t, map[string]openapi3.ParameterIn{
+		"id":          openapi3.ParameterInPath,
+		"dry_run":     openapi3.ParameterInQuery,
+		"X-Tenant-ID": openapi3.ParameterInHeader,
+	}, params)
+
+	assert.Contains(t, operation.RequestBody.RequestBody.Content, "application/json")
+	assert.Contains(t, operation.Responses.MapOfResponseOrRefValues, "200")
+	assert.Contains(t, operation.Responses.MapOfResponseOrRefValues, "400")
+}
@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/mwm-io/gapi/errors"
+)
+
+// Close codes defined by RFC 6455, section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+	CloseTryAgainLater    = 1013
+)
+
+// maxCloseReasonLength is the maximum length of a close reason: a control frame payload can't exceed 125 bytes,
+// including the 2 bytes of the close code.
+const maxCloseReasonLength = 123
+
+// CloseError is returned when the connection is closed with a close frame, either by the client or because of a protocol error.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// IsNormalClosure returns true if err is nil, or if the connection has been closed normally
+// (with CloseNormalClosure, CloseGoingAway or CloseNoStatusReceived).
+func IsNormalClosure(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var closeErr *CloseError
+	if !stdErrors.As(err, &closeErr) {
+		return false
+	}
+
+	switch closeErr.Code {
+	case CloseNormalClosure, CloseGoingAway, CloseNoStatusReceived:
+		return true
+	default:
+		return false
+	}
+}
+
+// CloseCode returns the close code and the close reason to use to close a connection because of the given error:
+//
+//   - nil: CloseNormalClosure
+//   - a *CloseError: its code and reason
+//   - a context error (the connection or the server is closing): CloseGoingAway
+//   - an errors.Error with a 4xx status: 4000 + status (ie: 4401 for errors.Unauthorized) and the error message
+//   - an errors.Error with a 503 status: CloseTryAgainLater and the error message
+//   - any other error: CloseInternalError
+func CloseCode(err error) (int, string) {
+	if err == nil {
+		return CloseNormalClosure, ""
+	}
+
+	var closeErr *CloseError
+	if stdErrors.As(err, &closeErr) {
+		return closeErr.Code, closeErr.Reason
+	}
+
+	if stdErrors.Is(err, context.Canceled) || stdErrors.Is(err, context.DeadlineExceeded) {
+		return CloseGoingAway, ""
+	}
+
+	gErr, ok := err.(errors.Error)
+	if !ok {
+		return CloseInternalError, ""
+	}
+
+	status := gErr.StatusCode()
+	switch {
+	case status >= 400 && status < 500:
+		return 4000 + status, truncateReason(gErr.Message())
+	case status == http.StatusServiceUnavailable:
+		return CloseTryAgainLater, truncateReason(gErr.Message())
+	default:
+		return CloseInternalError, ""
+	}
+}
+
+// truncateReason truncates the reason to maxCloseReasonLength bytes, without splitting a rune.
+func truncateReason(reason string) string {
+	if len(reason) <= maxCloseReasonLength {
+		return reason
+	}
+
+	reason = reason[:maxCloseReasonLength]
+	for len(reason) > 0 && !utf8.ValidString(reason) {
+		reason = reason[:len(reason)-1]
+	}
+
+	return reason
+}
@@ -0,0 +1,414 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	stdErrors "errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// Message types defined by RFC 6455, section 5.6.
+const (
+	TextMessage   MessageType = opText
+	BinaryMessage MessageType = opBinary
+)
+
+// Frame opcodes defined by RFC 6455, section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// maxControlPayloadLength is the maximum payload length of a control frame.
+const maxControlPayloadLength = 125
+
+// ErrClosed is returned when writing to a connection that has been closed.
+var ErrClosed = stdErrors.New("websocket: connection closed")
+
+// Conn is an upgraded websocket connection.
+//
+// One goroutine can read messages while others write messages: reads and writes are safe to use concurrently.
+// Pings from the client are answered while reading messages.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	options     Options
+	subprotocol string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+	// closeSent is true once a close frame has been sent: no frame can be written anymore.
+	closeSent bool
+	closeOnce sync.Once
+}
+
+// Context returns the context of the connection, with the values of the request context.
+// It is cancelled when the connection is closed or when the server shuts down. (see WithServerContext)
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake, or an empty string.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the network address of the client.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// start starts the keepalive pings and unblocks the reads once the connection context is done.
+func (c *Conn) start() {
+	c.extendReadDeadline()
+
+	go func() {
+		var pings <-chan time.Time
+		if c.options.PingInterval > 0 {
+			ticker := time.NewTicker(c.options.PingInterval)
+			defer ticker.Stop()
+
+			pings = ticker.C
+		}
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				_ = c.conn.SetReadDeadline(time.Now())
+				return
+			case <-pings:
+				if err := c.Ping(nil); err != nil {
+					c.cancel()
+				}
+			}
+		}
+	}()
+}
+
+// extendReadDeadline sets the read deadline from the ping interval: if the client doesn't answer a ping in time,
+// the read fails.
+func (c *Conn) extendReadDeadline() {
+	if c.options.PingInterval <= 0 {
+		return
+	}
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.options.PingInterval + c.options.PongTimeout))
+}
+
+// ReadMessage reads the next data message.
+// It returns a *CloseError if the client closed the connection, or if it didn't respect the protocol.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	var (
+		messageType MessageType
+		message     []byte
+	)
+
+	for {
+		fin, opcode, payload, err := c.readFrame(c.options.ReadLimit - int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+
+			continue
+
+		case opPong:
+			continue
+
+		case opClose:
+			return 0, nil, c.handleClose(payload)
+
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected a continuation frame")
+			}
+
+			messageType = MessageType(opcode)
+
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		message = append(message, payload...)
+
+		if !fin {
+			continue
+		}
+
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 text message")
+		}
+
+		return messageType, message, nil
+	}
+}
+
+// WriteMessage writes a data message.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return stdErrors.New("websocket: invalid message type")
+	}
+
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Receive reads the next data message and decodes it into v with the Decoder of the connection.
+func (c *Conn) Receive(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	return c.options.Decoder.Unmarshal(data, v)
+}
+
+// Send encodes v with the Encoder of the connection and writes it as a data message.
+func (c *Conn) Send(v interface{}) error {
+	data, err := c.options.Encoder.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteMessage(c.options.MessageType, data)
+}
+
+// Receive reads the next data message of the connection and decodes it into a new T.
+func Receive[T any](c *Conn) (T, error) {
+	var v T
+	err := c.Receive(&v)
+
+	return v, err
+}
+
+// Ping sends a ping to the client. The payload can't exceed 125 bytes.
+func (c *Conn) Ping(payload []byte) error {
+	return c.writeFrame(opPing, payload)
+}
+
+// Close sends a close frame with the given code and reason, and closes the connection.
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, truncateReason(reason)...)
+
+	err := c.writeFrame(opClose, payload)
+	if err == ErrClosed {
+		err = nil
+	}
+
+	c.closeOnce.Do(func() {
+		c.cancel()
+		if errClose := c.conn.Close(); err == nil {
+			err = errClose
+		}
+	})
+
+	return err
+}
+
+// CloseWithError closes the connection with the close code and reason computed from err. (see CloseCode)
+func (c *Conn) CloseWithError(err error) error {
+	code, reason := CloseCode(err)
+
+	return c.Close(code, reason)
+}
+
+// handleClose answers to a close frame received from the client, and returns the corresponding *CloseError.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(CloseInvalidPayload, "invalid UTF-8 close reason")
+		}
+	}
+
+	code := closeErr.Code
+	if code == CloseNoStatusReceived {
+		code = CloseNormalClosure
+	}
+
+	_ = c.Close(code, "")
+
+	return closeErr
+}
+
+// fail closes the connection because of a protocol error, and returns the corresponding *CloseError.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.Close(code, reason)
+
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// readError converts an error from the underlying connection.
+func (c *Conn) readError(err error) error {
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &CloseError{Code: CloseAbnormalClosure, Reason: "unexpected EOF"}
+	}
+
+	return err
+}
+
+// readFrame reads the next frame. The payload of a data frame can't exceed limit.
+func (c *Conn) readFrame(limit int64) (fin bool, opcode byte, payload []byte, err error) {
+	var header [8]byte
+	if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+		return false, 0, nil, c.readError(err)
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits must not be set")
+	}
+
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+			return false, 0, nil, c.readError(err)
+		}
+
+		length = int64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, header[:8]); err != nil {
+			return false, 0, nil, c.readError(err)
+		}
+
+		length = int64(binary.BigEndian.Uint64(header[:8]))
+		if length < 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	if opcode >= opClose {
+		if !fin || length > maxControlPayloadLength {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+		}
+	} else if length > limit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, c.readError(err)
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, c.readError(err)
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	c.extendReadDeadline()
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes a single unmasked frame.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	if opcode >= opClose && len(payload) > maxControlPayloadLength {
+		return stdErrors.New("websocket: control frame payload too long")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if c.options.WriteTimeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout))
+	}
+
+	if opcode == opClose {
+		c.closeSent = true
+	}
+
+	buffers := net.Buffers{header, payload}
+	if _, err := buffers.WriteTo(c.conn); err != nil {
+		c.closeSent = true
+		c.cancel()
+		return err
+	}
+
+	return nil
+}
+
+// validCloseCode returns true if the close code can be sent in a close frame. (RFC 6455, section 7.4)
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code == CloseNoStatusReceived || code == CloseAbnormalClosure || code == 1015:
+		return false
+	default:
+		return code >= CloseNormalClosure && code <= CloseTryAgainLater && code != 1004
+	}
+}
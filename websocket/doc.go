@@ -0,0 +1,30 @@
+/*
+Package websocket is a dependency-free implementation of the server side of the WebSocket protocol (RFC 6455).
+
+Upgrade upgrades an http request to a Conn, which reads and writes messages, encoded with an Encoder and a Decoder
+(JSON by default), answers to the pings of the client and sends its own pings to keep the connection alive.
+
+The connection is usually served by a handler.WebSocket, so that the gapi middlewares are executed for the upgrade request:
+
+	server.AddHandler(r, http.MethodGet, "/chat", &handler.WebSocket{
+		Handle: func(ctx context.Context, conn *websocket.Conn) error {
+			for {
+				msg, err := websocket.Receive[chatMessage](conn)
+				if err != nil {
+					return err
+				}
+
+				if msg.Text == "" {
+					return errors.BadRequest("empty_message", "message can't be empty")
+				}
+
+				if err := conn.Send(msg); err != nil {
+					return err
+				}
+			}
+		},
+	})
+
+When the handler returns, the connection is closed with a close code computed from the returned error. (see CloseCode)
+*/
+package websocket
@@ -0,0 +1,307 @@
+package websocket
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mwm-io/gapi/errors"
+)
+
+// Default values of Options.
+const (
+	DefaultReadLimit    = 1 << 20
+	DefaultPingInterval = 30 * time.Second
+	DefaultPongTimeout  = 10 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+// acceptGUID is used to compute the Sec-WebSocket-Accept header. (RFC 6455, section 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Encoder is able to marshal the messages sent with Conn.Send. (ie: middleware.EncoderByContentType["application/json"])
+type Encoder interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// Decoder is able to unmarshal the messages received with Conn.Receive. (ie: middleware.DecoderByContentType["application/json"])
+type Decoder interface {
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// jsonCodec is the codec of the connections upgraded without codec. (see WithCodec)
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+type codecKey struct{}
+
+// codec is the Encoder and the Decoder carried by a Context.
+type codec struct {
+	encoder Encoder
+	decoder Decoder
+}
+
+// WithCodec returns a new Context carrying the Encoder and the Decoder of the messages of the connections
+// upgraded from a request with this Context, unless set in their Options.
+// It is set by middleware.ResponseWriter, with the encoder of the response content type
+// and the matching middleware.DecoderByContentType, so that the messages use the same codec as the http responses.
+func WithCodec(ctx context.Context, encoder Encoder, decoder Decoder) context.Context {
+	return context.WithValue(ctx, codecKey{}, codec{encoder: encoder, decoder: decoder})
+}
+
+type serverContextKey struct{}
+
+// WithServerContext returns a new Context carrying the context of the server serving the request:
+// the connections upgraded from a request with this Context are closed when serverCtx is done.
+// It is set by the gapi servers, so that the connections are closed when the server shuts down. (see server.AddServer)
+func WithServerContext(ctx, serverCtx context.Context) context.Context {
+	return context.WithValue(ctx, serverContextKey{}, serverCtx)
+}
+
+// detachedContext is a context.Context keeping the values of its parent, without its deadline and its cancellation.
+// The connection outlives the upgrade request: a request timeout must not close it.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline implements the context.Context interface
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done implements the context.Context interface
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err implements the context.Context interface
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value implements the context.Context interface
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// Options configures a Conn. The zero value uses the default values.
+type Options struct {
+	// Subprotocols are the subprotocols supported by the server, by order of preference.
+	Subprotocols []string
+	// CheckOrigin returns false if the Origin of the request is not allowed.
+	// By default, only the requests without Origin or with the same origin as the Host are allowed.
+	CheckOrigin func(r *http.Request) bool
+	// Encoder encodes the messages sent with Conn.Send.
+	// Default to the Encoder carried by the request context (see WithCodec), or JSON.
+	Encoder Encoder
+	// Decoder decodes the messages received with Conn.Receive.
+	// Default to the Decoder carried by the request context (see WithCodec), or JSON.
+	Decoder Decoder
+	// MessageType is the type of the messages sent with Conn.Send. Default to TextMessage.
+	MessageType MessageType
+	// ReadLimit is the maximum size of a received message. Default to DefaultReadLimit.
+	ReadLimit int64
+	// PingInterval is the interval between two pings sent to the client. Default to DefaultPingInterval, negative disables the pings.
+	PingInterval time.Duration
+	// PongTimeout is the time to wait for a pong after a ping before closing the connection. Default to DefaultPongTimeout.
+	PongTimeout time.Duration
+	// WriteTimeout is the maximum duration of a write. Default to DefaultWriteTimeout, negative disables the timeout.
+	WriteTimeout time.Duration
+}
+
+func (o Options) withDefaults(ctx context.Context) Options {
+	if o.CheckOrigin == nil {
+		o.CheckOrigin = sameOrigin
+	}
+
+	c, _ := ctx.Value(codecKey{}).(codec)
+	if o.Encoder == nil {
+		o.Encoder = c.encoder
+	}
+
+	if o.Decoder == nil {
+		o.Decoder = c.decoder
+	}
+
+	if o.Encoder == nil {
+		o.Encoder = jsonCodec{}
+	}
+
+	if o.Decoder == nil {
+		o.Decoder = jsonCodec{}
+	}
+
+	if o.MessageType == 0 {
+		o.MessageType = TextMessage
+	}
+
+	if o.ReadLimit <= 0 {
+		o.ReadLimit = DefaultReadLimit
+	}
+
+	if o.PingInterval == 0 {
+		o.PingInterval = DefaultPingInterval
+	}
+
+	if o.PongTimeout <= 0 {
+		o.PongTimeout = DefaultPongTimeout
+	}
+
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = DefaultWriteTimeout
+	}
+
+	return o
+}
+
+// Upgrade upgrades the given request to a websocket connection.
+// The headers already set on w are added to the handshake response.
+//
+// The connection context keeps the values of the request context, but not its deadline and its cancellation:
+// the connection is not closed when the request times out. (ie: middleware.Timeout)
+//
+// If the request is not a valid websocket handshake, an errors.Error is returned and nothing is written,
+// so the error can be written by the ResponseWriter middleware.
+// Once upgraded, the connection is hijacked: nothing must be written with w anymore.
+func Upgrade(w http.ResponseWriter, r *http.Request, options Options) (*Conn, error) {
+	options = options.withDefaults(r.Context())
+
+	if r.Method != http.MethodGet {
+		return nil, errors.MethodNotAllowed("websocket_method_not_allowed", "websocket handshake must use the GET method")
+	}
+
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		return nil, errors.UpgradeRequired("websocket_upgrade_required", "websocket handshake must upgrade the connection to websocket")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, errors.UpgradeRequired("websocket_unsupported_version", "unsupported websocket version %s", r.Header.Get("Sec-WebSocket-Version"))
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, errors.BadRequest("websocket_invalid_key", "invalid Sec-WebSocket-Key header")
+	}
+
+	if !options.CheckOrigin(r) {
+		return nil, errors.Forbidden("websocket_origin_not_allowed", "origin %s not allowed", r.Header.Get("Origin"))
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.Err("websocket_hijack_unsupported", "the response writer doesn't support hijacking")
+	}
+
+	subprotocol := selectSubprotocol(r, options.Subprotocols)
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, errors.Wrap(err).WithKind("websocket_hijack_failed")
+	}
+
+	header := w.Header().Clone()
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", acceptKey(key))
+	if subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+
+	if options.WriteTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+	}
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	_ = header.Write(rw)
+	_, _ = rw.WriteString("\r\n")
+	if err := rw.Flush(); err != nil {
+		_ = netConn.Close()
+		return nil, errors.Wrap(err).WithKind("websocket_handshake_failed")
+	}
+
+	_ = netConn.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(detachedContext{parent: r.Context()})
+	if serverCtx, ok := r.Context().Value(serverContextKey{}).(context.Context); ok {
+		go func() {
+			select {
+			case <-serverCtx.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	conn := &Conn{
+		conn:        netConn,
+		br:          rw.Reader,
+		options:     options,
+		subprotocol: subprotocol,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	conn.start()
+
+	return conn, nil
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header value for the given Sec-WebSocket-Key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContainsToken returns true if the comma-separated header values contain the given token, case-insensitively.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// selectSubprotocol returns the first subprotocol supported by the server and requested by the client.
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, subprotocol := range supported {
+		if headerContainsToken(r.Header, "Sec-WebSocket-Protocol", subprotocol) {
+			return subprotocol
+		}
+	}
+
+	return ""
+}
+
+// sameOrigin returns true if the request has no Origin header, or if the Origin host is the request Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}